@@ -0,0 +1,81 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+)
+
+// AccountsService is an interface for reading account wide information with
+// the Rackspace Email API.
+type AccountsService interface {
+	Limits(context.Context) (*AccountLimits, *Response, error)
+}
+
+// AccountsServiceOp handles communication with the account related methods
+// of the Rackspace Email API.
+type AccountsServiceOp struct {
+	client *Client
+}
+
+var _ AccountsService = &AccountsServiceOp{}
+
+// AccountLimits represents the licensed mailboxes and the storage of an
+// account, summed over all of its domains. Storage is in megabytes.
+type AccountLimits struct {
+	Domains int
+
+	RSEmailMaxNumberMailboxes int
+	RSEmailUsedStorage        int
+	RSEmailExtraStorage       int
+
+	ExchangeMaxNumMailboxes int
+	ExchangeUsedStorage     int
+	ExchangeExtraStorage    int
+
+	ActiveSyncLicenses int
+	BlackBerryLicenses int
+}
+
+// Limits returns the licensed mailboxes and the storage of the account. The
+// API has no account level resource for these, so they are summed from the
+// domains on the account. The API does not report how many mailboxes are in
+// use, only how many are licensed.
+func (s *AccountsServiceOp) Limits(ctx context.Context) (*AccountLimits, *Response, error) {
+	domains, resp, err := s.client.Domains.Index(withAllPages(ctx), nil)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	limits := &AccountLimits{Domains: len(domains)}
+	for _, d := range domains {
+		limits.RSEmailMaxNumberMailboxes += d.RSEmailMaxNumberMailboxes
+		limits.RSEmailUsedStorage += d.RSEmailUsedStorage
+		limits.RSEmailExtraStorage += d.RSEmailExtraStorage
+		limits.ExchangeMaxNumMailboxes += d.ExchangeMaxNumMailboxes
+		limits.ExchangeUsedStorage += d.ExchangeUsedStorage
+		limits.ExchangeExtraStorage += d.ExchangeExtraStorage
+		limits.ActiveSyncLicenses += d.ActiveSyncLicenses
+		limits.BlackBerryLicenses += d.BlackBerryLicenses
+	}
+
+	return limits, resp, err
+}
@@ -0,0 +1,61 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAccounts_Limits(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"offset": 0, "size": 50, "total": 2, "domains": [
+			{"name": "foo.com", "serviceType": "rsemail", "rsEmailMaxNumberMailboxes": 10, "rsEmailUsedStorage": 2048, "rsEmailExtraStorage": 100},
+			{"name": "bar.com", "serviceType": "both", "rsEmailMaxNumberMailboxes": 5, "rsEmailUsedStorage": 512,
+			 "exchangeMaxNumMailboxes": 3, "exchangeUsedStorage": 1024, "activeSyncLicenses": 2, "blackBerryLicenses": 1}
+		]}`)
+	})
+
+	limits, _, err := client.Accounts.Limits(ctx)
+	if err != nil {
+		t.Fatalf("Accounts.Limits returned error: %v", err)
+	}
+
+	expected := &AccountLimits{
+		Domains:                   2,
+		RSEmailMaxNumberMailboxes: 15,
+		RSEmailUsedStorage:        2560,
+		RSEmailExtraStorage:       100,
+		ExchangeMaxNumMailboxes:   3,
+		ExchangeUsedStorage:       1024,
+		ActiveSyncLicenses:        2,
+		BlackBerryLicenses:        1,
+	}
+	if !reflect.DeepEqual(limits, expected) {
+		t.Errorf("Accounts.Limits returned %+v, expected %+v", limits, expected)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// adaptiveDecrease is the factor a limiter's rate is multiplied by
+	// after a 429 response.
+	adaptiveDecrease = 0.5
+
+	// adaptiveIncrease is the factor a reduced limiter's rate is multiplied
+	// by after each successful response, until it is back to its configured
+	// rate.
+	adaptiveIncrease = 1.05
+
+	// adaptiveMinLimit is the lowest rate, in requests per second, that a
+	// limiter is reduced to.
+	adaptiveMinLimit = 0.05
+)
+
+// adaptiveLimits lowers the rate of a limiter when the API responds with 429
+// Too Many Requests and slowly raises it back on success.
+type adaptiveLimits struct {
+	mu sync.Mutex
+
+	// Configured rate of each limiter that has been reduced
+	base map[*rate.Limiter]rate.Limit
+}
+
+func newAdaptiveLimits() *adaptiveLimits {
+	return &adaptiveLimits{base: make(map[*rate.Limiter]rate.Limit)}
+}
+
+// adjust updates the rate of l after a response with status.
+func (a *adaptiveLimits) adjust(l *rate.Limiter, status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	base, reduced := a.base[l]
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		if !reduced {
+			base = l.Limit()
+			a.base[l] = base
+		}
+		limit := l.Limit() * adaptiveDecrease
+		if limit < adaptiveMinLimit {
+			limit = adaptiveMinLimit
+		}
+		l.SetLimit(limit)
+	case reduced && status >= 200 && status <= 299:
+		limit := l.Limit() * adaptiveIncrease
+		if limit >= base {
+			limit = base
+			delete(a.base, l)
+		}
+		l.SetLimit(limit)
+	}
+}
+
+// SetAdaptiveRateLimit is a client option for adapting the rate limiters to
+// the API: after a 429 Too Many Requests response the rate of the limiter
+// that governed the request is halved, and each successful response raises it
+// by 5% until it is back to the rate set with SetGetLimiter or
+// SetPostLimiter. It is disabled by default.
+func SetAdaptiveRateLimit(adaptive bool) func(*Client) error {
+	return func(c *Client) error {
+		if adaptive {
+			c.adaptive = newAdaptiveLimits()
+		} else {
+			c.adaptive = nil
+		}
+		return nil
+	}
+}
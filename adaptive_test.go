@@ -0,0 +1,102 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSetAdaptiveRateLimit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetAdaptiveRateLimit(true)(client); err != nil {
+		t.Fatalf("SetAdaptiveRateLimit(): %v", err)
+	}
+	client.getLimiter = rate.NewLimiter(100, 10)
+
+	throttled := 2
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		if throttled > 0 {
+			throttled--
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Domains.Show(ctx, "foo.com"); err == nil {
+			t.Fatalf("Domains.Show should have returned an error for a 429")
+		}
+	}
+
+	if limit := client.getLimiter.Limit(); limit != 25 {
+		t.Errorf("Limit after two 429s = %v, expected %v", limit, 25)
+	}
+	if limit := client.putPostDeleteLimiter.Limit(); limit != defaultPutPostDeleteLimit {
+		t.Errorf("Mutate limit = %v, expected it to be unchanged", limit)
+	}
+
+	if _, _, err := client.Domains.Show(ctx, "foo.com"); err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	if limit := client.getLimiter.Limit(); limit <= 25 || limit >= 100 {
+		t.Errorf("Limit after a success = %v, expected it to recover slowly", limit)
+	}
+}
+
+func TestAdaptiveLimits_Recovery(t *testing.T) {
+	a := newAdaptiveLimits()
+	l := rate.NewLimiter(10, 1)
+
+	a.adjust(l, http.StatusTooManyRequests)
+	for i := 0; i < 100; i++ {
+		a.adjust(l, http.StatusOK)
+	}
+
+	if limit := l.Limit(); limit != 10 {
+		t.Errorf("Limit after sustained success = %v, expected %v", limit, 10)
+	}
+	if len(a.base) != 0 {
+		t.Errorf("Recovered limiters should no longer be tracked")
+	}
+}
+
+func TestSetAdaptiveRateLimit_Default(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	client.Domains.Show(ctx, "foo.com")
+
+	if limit := client.getLimiter.Limit(); limit != defaultGetLimit {
+		t.Errorf("Limit = %v, expected the non-adaptive default to be unchanged", limit)
+	}
+}
@@ -21,10 +21,17 @@
 package reago
 
 import (
+	"bufio"
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/mail"
 	"strings"
+	"sync"
 )
 
 const rackspaceEmailAliasesBasePath = "v1/domains/%s/rs/aliases"
@@ -35,24 +42,75 @@ const rackspaceEmailAliasesBasePath = "v1/domains/%s/rs/aliases"
 // See: http://api-wiki.apps.rackspace.com/api-wiki/index.php?title=Rackspace_Alias(Rest_API)
 type RackspaceEmailAliasesService interface {
 	Add(context.Context, string, string, []string) (*Response, error)
+	AddIfNotExists(context.Context, string, string, []string) (*Response, error)
 	Delete(context.Context, string, string) (*Response, error)
+	Exists(context.Context, string, string) (bool, *Response, error)
 	Show(context.Context, string, string) (*RackspaceEmailAliasShow, *Response, error)
 	Index(context.Context, *PageOptions, string) ([]RackspaceEmailAlias, *Response, error)
+	IndexWithTotal(context.Context, *PageOptions, string) ([]RackspaceEmailAlias, int, *Response, error)
+	Rename(context.Context, string, string, string) (*Response, error)
+	NearMemberLimit(context.Context, string, int) ([]RackspaceEmailAlias, *Response, error)
+	ImportCSV(context.Context, string, io.Reader) ([]BatchResult, error)
+	Disable(context.Context, string, string) ([]string, *Response, error)
+	Enable(context.Context, string, string, []string) (*Response, error)
+	AddressMap(context.Context, string) (map[string][]string, *Response, error)
+	ShowMany(context.Context, string, []string, int) (map[string]*RackspaceEmailAliasShow, map[string]error)
+	ExportNDJSON(context.Context, string, io.Writer) error
+	DeleteAll(context.Context, string, string) (int, error)
+	Count(context.Context, string) (int, *Response, error)
 }
 
 // RackspaceEmailAliasesServiceOp handles communication with the rackspace
 // email alias related methods of the Rackspace Email API.
 type RackspaceEmailAliasesServiceOp struct {
-	client *Client
+	client   *Client
+	basePath string
+
+	// Members of the aliases removed by Disable, keyed by domain and alias
+	disabled *aliasStash
+}
+
+// aliasStash holds the members of disabled aliases. A nil members entry
+// reserves an alias that is being disabled.
+type aliasStash struct {
+	mu      sync.Mutex
+	members map[string][]string
+}
+
+func newAliasStash() *aliasStash {
+	return &aliasStash{members: make(map[string][]string)}
 }
 
 var _ RackspaceEmailAliasesService = &RackspaceEmailAliasesServiceOp{}
 
+func (s *RackspaceEmailAliasesServiceOp) setBasePath(path string) {
+	s.basePath = path
+}
+
 // RackspaceEmailAlias represents a Rackspace Email API alias from the Index
 // method.
 type RackspaceEmailAlias struct {
 	Name            string `json:"name"`
 	NumberOfMembers int    `json:"numberOfMembers"`
+
+	// Extra holds the fields returned by the API that are not modeled above
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes an alias, keeping unmodeled fields in Extra.
+func (a *RackspaceEmailAlias) UnmarshalJSON(data []byte) error {
+	type alias RackspaceEmailAlias
+	if err := json.Unmarshal(data, (*alias)(a)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, a)
+	if err != nil {
+		return err
+	}
+	a.Extra = extra
+
+	return nil
 }
 
 // EmailAddress represents an array of email addresses that iare tied to a
@@ -65,64 +123,226 @@ type EmailAddress struct {
 type RackspaceEmailAliasShow struct {
 	Name             string       `json:"name"`
 	EmailAddressList EmailAddress `json:"emailAddressList"`
+
+	// CreatedDate and LastModified are nil if the API did not return them
+	CreatedDate  *Timestamp `json:"createdDate,omitempty"`
+	LastModified *Timestamp `json:"lastModified,omitempty"`
+
+	// Extra holds the fields returned by the API that are not modeled above
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes an alias, keeping unmodeled fields in Extra.
+func (a *RackspaceEmailAliasShow) UnmarshalJSON(data []byte) error {
+	type alias RackspaceEmailAliasShow
+	if err := json.Unmarshal(data, (*alias)(a)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, a)
+	if err != nil {
+		return err
+	}
+	a.Extra = extra
+
+	return nil
 }
 
 type rackspaceEmailAliasesRoot struct {
-	Offset                int                   `struct:"offset"`
-	Size                  int                   `struct:"size"`
-	Total                 int                   `struct:"total"`
+	page
 	RackspaceEmailAliases []RackspaceEmailAlias `json:"aliases"`
 }
 
+// aliasCSVHeader is the header row expected by ImportCSV.
+var aliasCSVHeader = []string{"alias", "addresses"}
+
+// aliasExport is the line written for each alias by ExportNDJSON.
+type aliasExport struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
 type rackspaceEmailAliasAddRequest struct {
 	RackspaceEmailAliasEmails string `json:"aliasEmails"`
 }
 
-// Index lists all Rackspace Email aliases
+// Index lists all Rackspace Email aliases. If fetching a page fails, the
+// aliases from the pages fetched before it are returned along with the error.
 func (s RackspaceEmailAliasesServiceOp) Index(ctx context.Context, opt *PageOptions, domain string) ([]RackspaceEmailAlias, *Response, error) {
+	aliases, _, resp, err := s.index(ctx, opt, domain)
+	return aliases, resp, err
+}
+
+// IndexWithTotal lists all Rackspace Email aliases like Index and also
+// returns the total number of aliases reported by the API on the last page
+// fetched. The total can differ from the number of aliases returned if
+// aliases were added or removed while paging.
+func (s RackspaceEmailAliasesServiceOp) IndexWithTotal(ctx context.Context, opt *PageOptions, domain string) ([]RackspaceEmailAlias, int, *Response, error) {
+	return s.index(ctx, opt, domain)
+}
+
+func (s RackspaceEmailAliasesServiceOp) index(ctx context.Context, opt *PageOptions, domain string) ([]RackspaceEmailAlias, int, *Response, error) {
 	var aliases []RackspaceEmailAlias
+	var total int
 	var resp *Response
 	var err error
 
+	domain = s.client.resolveDomain(domain)
 	if len(domain) < 1 {
-		return nil, nil, NewArgError("domain", "it cannot be an empty string")
+		return nil, 0, nil, NewArgError("domain", "it cannot be an empty string")
 	}
 
 	if opt == nil {
 		opt = &PageOptions{Size: defaultPageSize}
 	}
+	if opt.Size == 0 {
+		opt.Size = defaultPageSize
+	}
+	if opt.Size > maxPageSize {
+		return nil, 0, nil, NewArgError("opt.Size", fmt.Sprintf("it cannot be larger than %d", maxPageSize))
+	}
 
 	for {
-		path := fmt.Sprintf(rackspaceEmailAliasesBasePath, domain)
+		path := fmt.Sprintf(s.basePath, domain)
 		path, err = addOptions(path, opt)
 		if err != nil {
-			return nil, nil, err
+			return aliases, total, resp, err
 		}
 
 		req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
 		if err != nil {
-			return nil, nil, err
+			return aliases, total, resp, err
 		}
 
 		root := new(rackspaceEmailAliasesRoot)
-		resp, err := s.client.Do(ctx, req, root)
+		resp, err = s.client.Do(ctx, req, root)
 		if err != nil {
-			return nil, resp, err
+			return aliases, total, resp, err
 		}
 		aliases = append(aliases, root.RackspaceEmailAliases...)
+		total = root.Total
+
+		if s.client.onePage(ctx) {
+			break
+		}
+
+		if s.client.pagePrefetch > 1 {
+			if offsets, ok := s.client.remainingOffsets(root.page); ok {
+				var more []RackspaceEmailAlias
+				more, resp, err = s.prefetchPages(ctx, fmt.Sprintf(s.basePath, domain), *opt, offsets, resp)
+				return append(aliases, more...), total, resp, err
+			}
+		}
 
-		if root.Total <= root.Size+root.Offset {
+		next, more := s.client.advance(root.page)
+		if !more {
 			break
 		}
-		opt.Offset = root.Size + root.Offset
+		opt.Offset = next
+	}
+
+	return aliases, total, resp, err
+}
+
+// prefetchPages fetches the pages of aliases at offsets concurrently and
+// returns their aliases in order, up to the first page that failed. resp is
+// returned unless a later page was fetched.
+func (s RackspaceEmailAliasesServiceOp) prefetchPages(ctx context.Context, path string, opt PageOptions, offsets []int, resp *Response) ([]RackspaceEmailAlias, *Response, error) {
+	roots := make([]*rackspaceEmailAliasesRoot, len(offsets))
+	resps := make([]*Response, len(offsets))
+
+	err := s.client.prefetch(ctx, offsets, func(ctx context.Context, i, offset int) error {
+		opt := opt
+		opt.Offset = offset
+		path, err := addOptions(path, &opt)
+		if err != nil {
+			return err
+		}
+
+		req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+
+		root := new(rackspaceEmailAliasesRoot)
+		resps[i], err = s.client.Do(ctx, req, root)
+		if err != nil {
+			return err
+		}
+		roots[i] = root
+		return nil
+	})
+
+	var aliases []RackspaceEmailAlias
+	for i, root := range roots {
+		if root == nil {
+			break
+		}
+		aliases = append(aliases, root.RackspaceEmailAliases...)
+		resp = resps[i]
 	}
 
 	return aliases, resp, err
 }
 
+// Count returns the number of Rackspace Email aliases in a domain as reported
+// by the API and requires a non-empty domain name. Only a single page of size
+// one is fetched.
+func (s RackspaceEmailAliasesServiceOp) Count(ctx context.Context, domain string) (int, *Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return 0, nil, NewArgError("domain", "cannot be an empty string")
+	}
+
+	path := fmt.Sprintf(s.basePath, domain)
+
+	root := new(rackspaceEmailAliasesRoot)
+	resp, err := s.client.get(ctx, path, &PageOptions{Size: 1}, root)
+	if err != nil {
+		return 0, resp, err
+	}
+
+	return root.Total, resp, err
+}
+
+// NearMemberLimit lists the Rackspace Email aliases with at least threshold
+// members and requires a non-empty domain name and a positive threshold. Only
+// the Index pages are fetched since they already carry the member counts.
+func (s RackspaceEmailAliasesServiceOp) NearMemberLimit(ctx context.Context, domain string, threshold int) ([]RackspaceEmailAlias, *Response, error) {
+	if threshold < 1 {
+		return nil, nil, NewArgError("threshold", "it must be greater than zero")
+	}
+
+	aliases, resp, err := s.Index(withAllPages(ctx), nil, domain)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var near []RackspaceEmailAlias
+	for _, alias := range aliases {
+		if alias.NumberOfMembers >= threshold {
+			near = append(near, alias)
+		}
+	}
+
+	return near, resp, err
+}
+
 // Show gets details of a Rackspace Email alias and requires a non-empty domain
 // name and a non-empty alias.
 func (s *RackspaceEmailAliasesServiceOp) Show(ctx context.Context, domain, alias string) (*RackspaceEmailAliasShow, *Response, error) {
+	show, resp, err := s.show(ctx, domain, alias)
+	if err != nil && s.client.showNotFound(err) {
+		return nil, resp, nil
+	}
+
+	return show, resp, err
+}
+
+// show is Show without SetShowNotFoundNil, for the methods that rely on a
+// missing alias being an error.
+func (s *RackspaceEmailAliasesServiceOp) show(ctx context.Context, domain, alias string) (*RackspaceEmailAliasShow, *Response, error) {
+	domain = s.client.resolveDomain(domain)
 	if len(domain) < 1 {
 		return nil, nil, NewArgError("domain", "cannot be an empty string")
 	}
@@ -131,7 +351,7 @@ func (s *RackspaceEmailAliasesServiceOp) Show(ctx context.Context, domain, alias
 		return nil, nil, NewArgError("alias", "cannot be an empty string")
 	}
 
-	path := fmt.Sprintf(rackspaceEmailAliasesBasePath, domain)
+	path := fmt.Sprintf(s.basePath, domain)
 	path = fmt.Sprintf("%s/%s", path, alias)
 
 	req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
@@ -148,9 +368,41 @@ func (s *RackspaceEmailAliasesServiceOp) Show(ctx context.Context, domain, alias
 	return root, resp, err
 }
 
+// Exists reports whether a Rackspace Email alias exists and requires a
+// non-empty domain name and a non-empty alias. It issues a HEAD request so the
+// alias itself is not downloaded.
+func (s *RackspaceEmailAliasesServiceOp) Exists(ctx context.Context, domain, alias string) (bool, *Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return false, nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(alias) < 1 {
+		return false, nil, NewArgError("alias", "cannot be an empty string")
+	}
+
+	path := fmt.Sprintf(s.basePath, domain)
+	path = fmt.Sprintf("%s/%s", path, alias)
+
+	req, err := s.client.NewRequest(ctx, http.MethodHead, path, nil)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resp, err := s.client.Do(ctx, req, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return false, resp, nil
+		}
+		return false, resp, err
+	}
+
+	return true, resp, err
+}
+
 // Add adds a new Rackspace Email alias and requires a non-empty domain name
 // and a non-empty alias and a slice of email addresses.
 func (s *RackspaceEmailAliasesServiceOp) Add(ctx context.Context, domain, alias string, emailAddresses []string) (*Response, error) {
+	domain = s.client.resolveDomain(domain)
 	if len(domain) < 1 {
 		return nil, NewArgError("domain", "cannot be an empty string")
 	}
@@ -161,9 +413,13 @@ func (s *RackspaceEmailAliasesServiceOp) Add(ctx context.Context, domain, alias
 		return nil, NewArgError("emailAddresses", "cannot be an empty list of strings")
 	}
 
-	body := map[string]string{"aliasEmails": strings.Join(emailAddresses, ",")}
+	aliasEmails, err := JoinAliasEmails(emailAddresses)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]string{"aliasEmails": aliasEmails}
 
-	path := fmt.Sprintf(rackspaceEmailAliasesBasePath, domain)
+	path := fmt.Sprintf(s.basePath, domain)
 	path = fmt.Sprintf("%s/%s", path, alias)
 
 	req, err := s.client.NewRequest(ctx, http.MethodPost, path, body)
@@ -178,9 +434,37 @@ func (s *RackspaceEmailAliasesServiceOp) Add(ctx context.Context, domain, alias
 	return resp, err
 }
 
+// AddIfNotExists adds a new Rackspace Email alias like Add, but returns
+// ErrAlreadyExists instead of modifying an alias that already exists. The
+// check and the create are separate requests, so this is best-effort: an alias
+// created by someone else in between will still be modified.
+func (s *RackspaceEmailAliasesServiceOp) AddIfNotExists(ctx context.Context, domain, alias string, emailAddresses []string) (*Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(alias) < 1 {
+		return nil, NewArgError("alias", "cannot be an empty string")
+	}
+	if len(emailAddresses) < 1 {
+		return nil, NewArgError("emailAddresses", "cannot be an empty list of strings")
+	}
+
+	_, resp, err := s.show(withExistenceCheck(WithCacheBypass(ctx)), domain, alias)
+	if err == nil {
+		return resp, ErrAlreadyExists
+	}
+	if !isNotFound(err) {
+		return resp, err
+	}
+
+	return s.Add(ctx, domain, alias, emailAddresses)
+}
+
 // Delete removes a Rackspace Email alias and requires a non-empty domain name
 // and a non-empty alias.
 func (s *RackspaceEmailAliasesServiceOp) Delete(ctx context.Context, domain, alias string) (*Response, error) {
+	domain = s.client.resolveDomain(domain)
 	if len(domain) < 1 {
 		return nil, NewArgError("domain", "cannot be an empty string")
 	}
@@ -188,7 +472,7 @@ func (s *RackspaceEmailAliasesServiceOp) Delete(ctx context.Context, domain, ali
 		return nil, NewArgError("alias", "cannot be an empty string")
 	}
 
-	path := fmt.Sprintf(rackspaceEmailAliasesBasePath, domain)
+	path := fmt.Sprintf(s.basePath, domain)
 	path = fmt.Sprintf("%s/%s", path, alias)
 
 	req, err := s.client.NewRequest(ctx, http.MethodDelete, path, nil)
@@ -200,3 +484,616 @@ func (s *RackspaceEmailAliasesServiceOp) Delete(ctx context.Context, domain, ali
 
 	return resp, err
 }
+
+// Rename renames a Rackspace Email alias and requires a non-empty domain name,
+// a non-empty old alias and a different, non-empty new alias. The API has no
+// rename operation so the members of the old alias are copied to a new alias
+// and the old alias is only deleted once the new one has been created. The new
+// alias is created with AddIfNotExists, so ErrAlreadyExists is returned rather
+// than merging into an existing alias. If deleting the old alias fails, the
+// new alias is deleted again so that the domain is left as it was.
+func (s *RackspaceEmailAliasesServiceOp) Rename(ctx context.Context, domain, oldAlias, newAlias string) (*Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(oldAlias) < 1 {
+		return nil, NewArgError("oldAlias", "cannot be an empty string")
+	}
+	if len(newAlias) < 1 {
+		return nil, NewArgError("newAlias", "cannot be an empty string")
+	}
+	if oldAlias == newAlias {
+		return nil, NewArgError("newAlias", "it is the same as oldAlias")
+	}
+
+	alias, resp, err := s.show(WithCacheBypass(ctx), domain, oldAlias)
+	if err != nil {
+		return resp, err
+	}
+
+	resp, err = s.AddIfNotExists(ctx, domain, newAlias, alias.EmailAddressList.Addresses)
+	if err != nil {
+		return resp, err
+	}
+
+	// newAlias was created by this call, so it is safe to roll it back.
+	resp, err = s.Delete(ctx, domain, oldAlias)
+	if err != nil {
+		if _, rerr := s.Delete(ctx, domain, newAlias); rerr != nil {
+			return resp, fmt.Errorf("%w (rolling back %s also failed: %v)", err, newAlias, rerr)
+		}
+		return resp, err
+	}
+
+	return resp, err
+}
+
+// JoinAliasEmails validates the member addresses of an alias and joins them
+// into the comma separated form the API expects. Addresses are trimmed and
+// must be bare addresses such as "foo@bar.com", without a display name.
+// Duplicates are compared case-insensitively and only the first is kept.
+func JoinAliasEmails(addrs []string) (string, error) {
+	if len(addrs) < 1 {
+		return "", NewArgError("addrs", "cannot be an empty list of strings")
+	}
+
+	var joined []string
+	seen := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		a = strings.TrimSpace(a)
+		parsed, err := mail.ParseAddress(a)
+		if err != nil || parsed.Address != a {
+			return "", NewArgError("addrs", fmt.Sprintf("%q is not an email address", a))
+		}
+
+		key := addressKey(a)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		joined = append(joined, a)
+	}
+
+	return strings.Join(joined, ","), nil
+}
+
+// addressKey is the key two member addresses are equal under: the address
+// lower cased as a whole. JoinAliasEmails, CanonicalizeAddresses and
+// AliasMemberDiff all compare addresses with it.
+func addressKey(addr string) string {
+	return strings.ToLower(addr)
+}
+
+// CanonicalizeAddresses returns the member addresses of an alias in a
+// canonical form, so that lists that only differ in presentation compare
+// equal. Addresses are trimmed, display names are removed ("Foo
+// <Foo@Bar.com>" becomes "Foo@bar.com"), the domain part is lower cased and
+// duplicates are removed, keeping the first. Addresses are duplicates if they
+// only differ in case, but the local part of the one kept keeps its case.
+// Empty addresses are dropped and addresses that cannot be parsed are only
+// trimmed, so that JoinAliasEmails can still report them.
+func CanonicalizeAddresses(addrs []string) []string {
+	var canonical []string
+	seen := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+
+		if parsed, err := mail.ParseAddress(a); err == nil {
+			a = parsed.Address
+			if at := strings.LastIndex(a, "@"); at >= 0 {
+				a = a[:at] + strings.ToLower(a[at:])
+			}
+		}
+
+		if seen[addressKey(a)] {
+			continue
+		}
+		seen[addressKey(a)] = true
+		canonical = append(canonical, a)
+	}
+
+	return canonical
+}
+
+// AliasMemberDiff compares the current members of an alias with the desired
+// members and returns the addresses that need to be added and removed.
+// Addresses are canonicalized with CanonicalizeAddresses and compared
+// case-insensitively, duplicates are ignored and the returned addresses are
+// in their normalized, lower case form.
+func AliasMemberDiff(current, desired []string) (added, removed []string) {
+	normalize := func(addrs []string) ([]string, map[string]bool) {
+		var list []string
+		set := make(map[string]bool, len(addrs))
+		for _, a := range CanonicalizeAddresses(addrs) {
+			a = addressKey(a)
+			set[a] = true
+			list = append(list, a)
+		}
+		return list, set
+	}
+
+	currentList, currentSet := normalize(current)
+	desiredList, desiredSet := normalize(desired)
+
+	for _, a := range desiredList {
+		if !currentSet[a] {
+			added = append(added, a)
+		}
+	}
+	for _, a := range currentList {
+		if !desiredSet[a] {
+			removed = append(removed, a)
+		}
+	}
+
+	return added, removed
+}
+
+// ImportCSV creates the Rackspace Email aliases read from r and requires a
+// non-empty domain name. The input must start with an "alias,addresses"
+// header followed by one alias per row, with its addresses separated by
+// semicolons. Quoted fields may span lines and a leading UTF-8 byte order
+// mark is ignored. Blank lines are skipped. Every other row gets a
+// BatchResult, numbered with the line it starts on, so a malformed row or a
+// failed create does not stop the import. An error is only returned if the
+// input cannot be read, the header is wrong or the context is cancelled.
+func (s *RackspaceEmailAliasesServiceOp) ImportCSV(ctx context.Context, domain string, r io.Reader) ([]BatchResult, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, NewArgError("domain", "cannot be an empty string")
+	}
+
+	if err := s.client.preflight(ctx); err != nil {
+		return nil, err
+	}
+
+	lines := newCSVLineReader(r)
+	reader := csv.NewReader(lines)
+	reader.FieldsPerRecord = -1
+
+	var results []BatchResult
+	header := false
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line := lines.recordLine(record)
+
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return results, err
+		}
+		if parseErr != nil {
+			line = parseErr.StartLine
+		} else if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		if !header {
+			if err != nil || len(record) != len(aliasCSVHeader) ||
+				!strings.EqualFold(strings.TrimSpace(record[0]), aliasCSVHeader[0]) ||
+				!strings.EqualFold(strings.TrimSpace(record[1]), aliasCSVHeader[1]) {
+				return nil, fmt.Errorf("line %d: expected header %q", line, strings.Join(aliasCSVHeader, ","))
+			}
+			header = true
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := BatchResult{Line: line}
+		if err != nil {
+			result.Err = fmt.Errorf("line %d: %w", line, err)
+			results = append(results, result)
+			continue
+		}
+		if len(record) != len(aliasCSVHeader) {
+			result.Err = fmt.Errorf("line %d: expected %d fields, got %d", line, len(aliasCSVHeader), len(record))
+			results = append(results, result)
+			continue
+		}
+
+		result.Name = strings.TrimSpace(record[0])
+		var addrs []string
+		for _, addr := range strings.Split(record[1], ";") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+
+		if _, err := s.Add(ctx, domain, result.Name, addrs); err != nil {
+			result.Err = fmt.Errorf("line %d: %w", line, err)
+		}
+		results = append(results, result)
+	}
+
+	if !header {
+		return nil, fmt.Errorf("expected header %q", strings.Join(aliasCSVHeader, ","))
+	}
+
+	return results, nil
+}
+
+// csvLineReader hands its input to a csv.Reader one line per Read, so that
+// the lines it has returned are exactly the lines the csv.Reader has used.
+// That lets ImportCSV number its records, quoted fields spanning lines
+// included. A leading UTF-8 byte order mark, as written by spreadsheets, is
+// dropped.
+type csvLineReader struct {
+	r *bufio.Reader
+
+	// Number of the line being read, counting from one
+	line      int
+	lineStart bool
+}
+
+func newCSVLineReader(r io.Reader) *csvLineReader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	return &csvLineReader{r: br, lineStart: true}
+}
+
+func (l *csvLineReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+
+		if l.lineStart {
+			l.line++
+			l.lineStart = false
+		}
+		p[n] = b
+		n++
+		if b == '\n' {
+			l.lineStart = true
+			break
+		}
+	}
+	return n, nil
+}
+
+// recordLine returns the line the record just read started on.
+func (l *csvLineReader) recordLine(record []string) int {
+	line := l.line
+	for _, field := range record {
+		line -= strings.Count(field, "\n")
+	}
+	return line
+}
+
+// setMembers replaces the members of an existing Rackspace Email alias.
+func (s *RackspaceEmailAliasesServiceOp) setMembers(ctx context.Context, domain, alias string, emailAddresses []string) (*Response, error) {
+	aliasEmails, err := JoinAliasEmails(emailAddresses)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]string{"aliasEmails": aliasEmails}
+
+	path := fmt.Sprintf(s.basePath, domain)
+	path = fmt.Sprintf("%s/%s", path, alias)
+
+	req, err := s.client.NewRequest(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(ctx, req, nil)
+}
+
+// Disable stops a Rackspace Email alias from delivering mail in a way that
+// Enable can undo and requires a non-empty domain name and a non-empty alias.
+// The API cannot disable an alias, so its members are replaced with the
+// placeholder address set with SetDisabledAliasPlaceholder. The alias itself
+// is kept, so its name cannot be taken while it is disabled. The replaced
+// members are returned and recorded in the client for Enable; callers that
+// need to enable the alias from another client or after a restart must keep
+// them and pass them to Enable.
+func (s *RackspaceEmailAliasesServiceOp) Disable(ctx context.Context, domain, alias string) ([]string, *Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(alias) < 1 {
+		return nil, nil, NewArgError("alias", "cannot be an empty string")
+	}
+
+	key := domain + "/" + alias
+	s.disabled.mu.Lock()
+	if _, ok := s.disabled.members[key]; ok {
+		s.disabled.mu.Unlock()
+		return nil, nil, NewArgError("alias", "it is already disabled")
+	}
+	s.disabled.members[key] = nil
+	s.disabled.mu.Unlock()
+
+	release := func() {
+		s.disabled.mu.Lock()
+		delete(s.disabled.members, key)
+		s.disabled.mu.Unlock()
+	}
+
+	show, resp, err := s.show(WithCacheBypass(ctx), domain, alias)
+	if err != nil {
+		release()
+		return nil, resp, err
+	}
+
+	placeholder := s.client.disabledAliasPlaceholder
+	members := show.EmailAddressList.Addresses
+	if len(members) == 1 && addressKey(members[0]) == addressKey(placeholder) {
+		release()
+		return nil, resp, NewArgError("alias", "it is already disabled")
+	}
+
+	resp, err = s.setMembers(ctx, domain, alias, []string{placeholder})
+	if err != nil {
+		release()
+		return nil, resp, err
+	}
+
+	s.disabled.mu.Lock()
+	s.disabled.members[key] = members
+	s.disabled.mu.Unlock()
+
+	return members, resp, err
+}
+
+// Enable restores the members of a Rackspace Email alias disabled by Disable
+// and requires a non-empty domain name and a non-empty alias. The members are
+// set to members, as returned by Disable, or to the members recorded by
+// Disable on this client if members is empty.
+func (s *RackspaceEmailAliasesServiceOp) Enable(ctx context.Context, domain, alias string, members []string) (*Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(alias) < 1 {
+		return nil, NewArgError("alias", "cannot be an empty string")
+	}
+
+	key := domain + "/" + alias
+	s.disabled.mu.Lock()
+	recorded := s.disabled.members[key]
+	if len(members) < 1 {
+		if recorded == nil {
+			s.disabled.mu.Unlock()
+			return nil, NewArgError("alias", "it was not disabled by this client and no members were given")
+		}
+		members = recorded
+	}
+	if recorded != nil {
+		delete(s.disabled.members, key)
+	}
+	s.disabled.mu.Unlock()
+
+	resp, err := s.setMembers(ctx, domain, alias, members)
+	if err != nil && recorded != nil {
+		// Keep the record so that Enable can be retried.
+		s.disabled.mu.Lock()
+		if _, ok := s.disabled.members[key]; !ok {
+			s.disabled.members[key] = recorded
+		}
+		s.disabled.mu.Unlock()
+	}
+
+	return resp, err
+}
+
+// AddressMap returns the member addresses of every Rackspace Email alias in a
+// domain, keyed by alias name, and requires a non-empty domain name. It makes
+// one Show call per alias on top of the Index pages, so it takes at least as
+// many seconds as the GET rate limit allows for that many requests.
+func (s *RackspaceEmailAliasesServiceOp) AddressMap(ctx context.Context, domain string) (map[string][]string, *Response, error) {
+	if err := s.client.preflight(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	aliases, resp, err := s.Index(withAllPages(ctx), nil, domain)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	addresses := make(map[string][]string, len(aliases))
+	for _, alias := range aliases {
+		if err := ctx.Err(); err != nil {
+			return nil, resp, err
+		}
+
+		var show *RackspaceEmailAliasShow
+		show, resp, err = s.show(ctx, domain, alias.Name)
+		if err != nil {
+			return nil, resp, err
+		}
+		addresses[alias.Name] = show.EmailAddressList.Addresses
+	}
+
+	return addresses, resp, err
+}
+
+// ShowMany gets the details of several Rackspace Email aliases at once, with
+// up to concurrency Show calls in flight. Every call still waits on the GET
+// rate limiter, so concurrency only hides the latency of each request rather
+// than raising the request rate. Each alias ends up in exactly one of the
+// returned maps: the details of the aliases that were fetched, or the error
+// for the ones that were not, including aliases that do not exist and
+// aliases that were not fetched because the context was cancelled or the
+// preflight status check failed.
+func (s *RackspaceEmailAliasesServiceOp) ShowMany(ctx context.Context, domain string, aliases []string, concurrency int) (map[string]*RackspaceEmailAliasShow, map[string]error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(map[string]*RackspaceEmailAliasShow, len(aliases))
+	errs := make(map[string]error)
+
+	if err := s.client.preflight(ctx); err != nil {
+		for _, name := range aliases {
+			errs[name] = err
+		}
+		return results, errs
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	names := make(chan string)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				show, _, err := s.show(ctx, domain, name)
+
+				mu.Lock()
+				if err != nil {
+					errs[name] = err
+				} else {
+					results[name] = show
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(aliases))
+	for _, name := range aliases {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			errs[name] = err
+			mu.Unlock()
+			continue
+		}
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+
+	return results, errs
+}
+
+// ExportNDJSON writes every Rackspace Email alias in a domain to w as
+// newline-delimited JSON and requires a non-empty domain name. Each line is an
+// object with the alias "name" and its "members". Lines are written, and w is
+// flushed if it has a Flush method, as each alias is fetched, so a failure
+// part way through leaves the aliases exported so far in w. Like AddressMap it
+// makes one Show call per alias.
+func (s *RackspaceEmailAliasesServiceOp) ExportNDJSON(ctx context.Context, domain string, w io.Writer) error {
+	if err := s.client.preflight(ctx); err != nil {
+		return err
+	}
+
+	aliases, _, err := s.Index(withAllPages(ctx), nil, domain)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, alias := range aliases {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		show, _, err := s.show(ctx, domain, alias.Name)
+		if err != nil {
+			return err
+		}
+
+		members := show.EmailAddressList.Addresses
+		if members == nil {
+			members = []string{}
+		}
+		if err := enc.Encode(aliasExport{Name: alias.Name, Members: members}); err != nil {
+			return err
+		}
+
+		switch f := w.(type) {
+		case interface{ Flush() error }:
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		case http.Flusher:
+			f.Flush()
+		}
+	}
+
+	return nil
+}
+
+// DeleteAll removes every Rackspace Email alias in a domain and returns the
+// number of aliases deleted. It is meant for tearing down test domains and,
+// as a guard against accidents, only proceeds when confirm is the domain
+// name. It requires a non-empty domain name. If a delete fails or the context
+// is cancelled, it stops and returns the number deleted so far with the error.
+func (s *RackspaceEmailAliasesServiceOp) DeleteAll(ctx context.Context, domain, confirm string) (int, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return 0, NewArgError("domain", "cannot be an empty string")
+	}
+	if confirm != domain {
+		return 0, NewArgError("confirm", "it must be the domain name")
+	}
+
+	if err := s.client.preflight(ctx); err != nil {
+		return 0, err
+	}
+
+	aliases, _, err := s.Index(withAllPages(WithCacheBypass(ctx)), nil, domain)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, alias := range aliases {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		if _, err := s.Delete(ctx, domain, alias.Name); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// VerifyAliasCount lists the Rackspace Email aliases of a domain and returns
+// an error if the number of aliases received does not match the total
+// reported by the API, which points at pages lost while paging. It requires a
+// non-empty domain name. A mismatch can also be caused by aliases being added
+// or removed during the listing, so it is best run when the domain is not
+// being changed.
+func (c *Client) VerifyAliasCount(ctx context.Context, domain string) error {
+	aliases, total, _, err := c.RackspaceEmailAliases.IndexWithTotal(withAllPages(ctx), nil, domain)
+	if err != nil {
+		return err
+	}
+
+	if len(aliases) != total {
+		return fmt.Errorf("alias count mismatch for %s: received %d aliases, the API reported %d",
+			c.resolveDomain(domain), len(aliases), total)
+	}
+
+	return nil
+}
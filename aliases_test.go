@@ -21,10 +21,19 @@
 package reago
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
 func TestRackspaceEmailAliases_Index(t *testing.T) {
@@ -81,6 +90,84 @@ func TestRackspaceEmailAliases_Index_MultiplePages(t *testing.T) {
 	}
 }
 
+func TestRackspaceEmailAliases_Index_ZeroSize(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if size := r.URL.Query().Get("size"); size != "50" {
+			t.Errorf("Request size = %v, expected %v", size, 50)
+		}
+		requests++
+		fmt.Fprint(w, `{"offset": 0, "size": 0, "total": 2, "aliases": [{"name":"foo"}]}`)
+	})
+
+	aliases, _, err := client.RackspaceEmailAliases.Index(ctx, &PageOptions{}, "domain.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Errorf("RackspaceEmailAliases.Index made %d requests, expected 1", requests)
+	}
+
+	expected := []RackspaceEmailAlias{{Name: "foo"}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("RackspaceEmailAliases.Index returned %+v, expected %+v", aliases, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_Index_PartialResults(t *testing.T) {
+	setup()
+	defer teardown()
+
+	index := 0
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		if index > 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 2, "aliases": [{"name":"foo"}]}`)
+		index++
+	})
+
+	aliases, resp, err := client.RackspaceEmailAliases.Index(ctx, &PageOptions{Size: 1}, "domain.com")
+	if err == nil {
+		t.Fatalf("RackspaceEmailAliases.Index should have returned an error")
+	}
+	if resp == nil || resp.Response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("RackspaceEmailAliases.Index should have returned the failed response")
+	}
+
+	expected := []RackspaceEmailAlias{{Name: "foo"}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("RackspaceEmailAliases.Index returned %+v, expected %+v", aliases, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_Index_MaxPageSize(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		if size := r.URL.Query().Get("size"); size != "250" {
+			t.Errorf("Request size = %v, expected %v", size, 250)
+		}
+		fmt.Fprint(w, `{"aliases": [{"name":"foo"}]}`)
+	})
+
+	if _, _, err := client.RackspaceEmailAliases.Index(ctx, &PageOptions{Size: maxPageSize}, "domain.com"); err != nil {
+		t.Errorf("RackspaceEmailAliases.Index returned error for the maximum page size: %v", err)
+	}
+
+	_, _, err := client.RackspaceEmailAliases.Index(ctx, &PageOptions{Size: maxPageSize + 1}, "domain.com")
+	if _, ok := err.(*ArgError); !ok {
+		t.Errorf("RackspaceEmailAliases.Index returned %v, expected an ArgError", err)
+	}
+}
+
 func TestRackspaceEmailAliases_Show_NoDomain(t *testing.T) {
 	_, _, err := client.RackspaceEmailAliases.Show(ctx, "", "foo")
 	if err == nil {
@@ -123,6 +210,32 @@ func TestRackspaceEmailAliases_Show(t *testing.T) {
 	}
 }
 
+func TestRackspaceEmailAliases_Show_Timestamps(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}, "createdDate": "2019-03-04T05:06:07Z", "lastModified": "/Date(1577934245000)/"}`)
+	})
+
+	alias, _, err := client.RackspaceEmailAliases.Show(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Show returned error: %v", err)
+	}
+
+	created := time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+	if alias.CreatedDate == nil || !alias.CreatedDate.Equal(created) {
+		t.Errorf("RackspaceEmailAliases.Show CreatedDate = %v, expected %v", alias.CreatedDate, created)
+	}
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if alias.LastModified == nil || !alias.LastModified.Equal(modified) {
+		t.Errorf("RackspaceEmailAliases.Show LastModified = %v, expected %v", alias.LastModified, modified)
+	}
+	if alias.Extra != nil {
+		t.Errorf("RackspaceEmailAliases.Show Extra = %v, expected the timestamps to be modeled", alias.Extra)
+	}
+}
+
 func TestRackspaceEmailAliases_Add_NoDomain(t *testing.T) {
 	_, err := client.RackspaceEmailAliases.Add(ctx, "", "foo", []string{"foo@bar.com"})
 	if err == nil {
@@ -150,14 +263,24 @@ func TestRackspaceEmailAliases_Add(t *testing.T) {
 
 	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodPost)
+		if got := r.FormValue("aliasEmails"); got != "foo@bar.com,baz@bar.com" {
+			t.Errorf("aliasEmails = %q, expected %q", got, "foo@bar.com,baz@bar.com")
+		}
 	})
 
-	_, err := client.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{"foo@bar.com"})
+	_, err := client.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{"foo@bar.com", "baz@bar.com", "foo@bar.com"})
 	if err != nil {
 		t.Errorf("RackspaceEmailAliases.Add returned error: %v", err)
 	}
 }
 
+func TestRackspaceEmailAliases_Add_InvalidAddress(t *testing.T) {
+	_, err := client.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{"foo"})
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.Add should have returned an error for an invalid address")
+	}
+}
+
 func TestRackspaceEmailAliases_Delete_NoDomain(t *testing.T) {
 	_, err := client.RackspaceEmailAliases.Delete(ctx, "", "foo")
 	if err == nil {
@@ -185,3 +308,1007 @@ func TestRackspaceEmailAliases_Delete(t *testing.T) {
 		t.Errorf("RackspaceEmailAliases.Delete returned error: %v", err)
 	}
 }
+
+func TestRackspaceEmailAliases_Rename_SameAlias(t *testing.T) {
+	_, err := client.RackspaceEmailAliases.Rename(ctx, "foo.com", "bar", "bar")
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.Rename should have returned an error for identical aliases")
+	}
+}
+
+func TestRackspaceEmailAliases_Rename(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var calls []string
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" bar")
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com", "qux@bar.com"]}}`)
+		}
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/baz", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" baz")
+		if r.Method == http.MethodGet {
+			http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+			return
+		}
+		if v := r.FormValue("aliasEmails"); v != "baz@bar.com,qux@bar.com" {
+			t.Errorf("Request aliasEmails = %v, expected %v", v, "baz@bar.com,qux@bar.com")
+		}
+	})
+
+	_, err := client.RackspaceEmailAliases.Rename(ctx, "foo.com", "bar", "baz")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Rename returned error: %v", err)
+	}
+
+	expected := []string{"GET bar", "GET baz", "POST baz", "DELETE bar"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("RackspaceEmailAliases.Rename made calls %v, expected %v", calls, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_Rename_RollBack(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var calls []string
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" bar")
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}}`)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/baz", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" baz")
+		if r.Method == http.MethodGet {
+			http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+		}
+	})
+
+	_, err := client.RackspaceEmailAliases.Rename(ctx, "foo.com", "bar", "baz")
+	if err == nil {
+		t.Fatalf("RackspaceEmailAliases.Rename should have returned an error")
+	}
+
+	expected := []string{"GET bar", "GET baz", "POST baz", "DELETE bar", "DELETE baz"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("RackspaceEmailAliases.Rename made calls %v, expected %v", calls, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_Rename_NewAliasExists(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var calls []string
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" bar")
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}}`)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/baz", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" baz")
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"name": "baz", "emailAddressList": {"emailAddress": ["other@bar.com"]}}`)
+		}
+	})
+
+	_, err := client.RackspaceEmailAliases.Rename(ctx, "foo.com", "bar", "baz")
+	if err != ErrAlreadyExists {
+		t.Fatalf("RackspaceEmailAliases.Rename returned %v, expected %v", err, ErrAlreadyExists)
+	}
+
+	// The existing baz is neither modified nor rolled back.
+	expected := []string{"GET bar", "GET baz"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("RackspaceEmailAliases.Rename made calls %v, expected %v", calls, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_AddIfNotExists(t *testing.T) {
+	setup()
+	defer teardown()
+
+	added := false
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case http.MethodPost:
+			added = true
+		}
+	})
+
+	_, err := client.RackspaceEmailAliases.AddIfNotExists(ctx, "foo.com", "bar", []string{"foo@bar.com"})
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.AddIfNotExists returned error: %v", err)
+	}
+	if !added {
+		t.Errorf("RackspaceEmailAliases.AddIfNotExists did not add the alias")
+	}
+}
+
+func TestRackspaceEmailAliases_AddIfNotExists_Exists(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}}`)
+	})
+
+	_, err := client.RackspaceEmailAliases.AddIfNotExists(ctx, "foo.com", "bar", []string{"foo@bar.com"})
+	if err != ErrAlreadyExists {
+		t.Errorf("RackspaceEmailAliases.AddIfNotExists returned %v, expected %v", err, ErrAlreadyExists)
+	}
+}
+
+func TestSetBasePath_DomainVerb(t *testing.T) {
+	for _, path := range []string{"staging/aliases", "staging/%d/aliases", "staging/%s/%s", "staging/%s/%v"} {
+		if _, err := New(nil, SetRackspaceEmailAliasesBasePath(path)); err == nil {
+			t.Errorf("SetRackspaceEmailAliasesBasePath should have returned an error for %q", path)
+		}
+		if _, err := New(nil, SetExchangeBasePath(path)); err == nil {
+			t.Errorf("SetExchangeBasePath should have returned an error for %q", path)
+		}
+	}
+
+	if _, err := New(nil, SetExchangeBasePath("staging/100%%/%s/ex/mailboxes")); err != nil {
+		t.Errorf("SetExchangeBasePath returned error: %v", err)
+	}
+}
+
+func TestRackspaceEmailAliases_BasePathOverride(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetRackspaceEmailAliasesBasePath("staging/domains/%s/rs/aliases")(client); err != nil {
+		t.Fatalf("SetRackspaceEmailAliasesBasePath(): %v", err)
+	}
+
+	mux.HandleFunc("/staging/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+	})
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	_, err := client.RackspaceEmailAliases.Delete(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Delete returned error: %v", err)
+	}
+
+	// Other services keep their default base path
+	_, _, err = client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Errorf("Domains.Show returned error: %v", err)
+	}
+}
+
+func TestRackspaceEmailAliases_Exists(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodHead)
+	})
+
+	exists, _, err := client.RackspaceEmailAliases.Exists(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Exists returned error: %v", err)
+	}
+	if !exists {
+		t.Errorf("RackspaceEmailAliases.Exists returned false, expected true")
+	}
+}
+
+func TestRackspaceEmailAliases_Exists_NotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodHead)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	exists, _, err := client.RackspaceEmailAliases.Exists(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Exists returned error: %v", err)
+	}
+	if exists {
+		t.Errorf("RackspaceEmailAliases.Exists returned true, expected false")
+	}
+}
+
+func TestRackspaceEmailAliases_Exists_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	_, _, err := client.RackspaceEmailAliases.Exists(ctx, "foo.com", "bar")
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.Exists should have returned an error")
+	}
+}
+
+func TestAliasMemberDiff(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, desired []string
+		added, removed   []string
+	}{
+		{
+			name:    "no changes",
+			current: []string{"a@foo.com", "b@foo.com"},
+			desired: []string{"b@foo.com", "a@foo.com"},
+		},
+		{
+			name:    "add and remove",
+			current: []string{"a@foo.com", "b@foo.com"},
+			desired: []string{"b@foo.com", "c@foo.com"},
+			added:   []string{"c@foo.com"},
+			removed: []string{"a@foo.com"},
+		},
+		{
+			name:    "duplicates",
+			current: []string{"a@foo.com", "a@foo.com"},
+			desired: []string{"b@foo.com", "b@foo.com", "a@foo.com"},
+			added:   []string{"b@foo.com"},
+		},
+		{
+			name:    "case and whitespace",
+			current: []string{"A@Foo.com", " b@foo.com"},
+			desired: []string{"a@foo.com ", "B@FOO.COM", "C@foo.com"},
+			added:   []string{"c@foo.com"},
+		},
+		{
+			name:    "display name",
+			current: []string{"a@foo.com"},
+			desired: []string{"Foo <a@Foo.com>"},
+		},
+		{
+			name:    "empty",
+			current: []string{"a@foo.com"},
+			removed: []string{"a@foo.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		added, removed := AliasMemberDiff(tt.current, tt.desired)
+		if !reflect.DeepEqual(added, tt.added) {
+			t.Errorf("%s: AliasMemberDiff added = %v, expected %v", tt.name, added, tt.added)
+		}
+		if !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("%s: AliasMemberDiff removed = %v, expected %v", tt.name, removed, tt.removed)
+		}
+	}
+}
+
+func TestJoinAliasEmails(t *testing.T) {
+	joined, err := JoinAliasEmails([]string{"a@foo.com", " b@foo.com "})
+	if err != nil {
+		t.Fatalf("JoinAliasEmails returned error: %v", err)
+	}
+	if expected := "a@foo.com,b@foo.com"; joined != expected {
+		t.Errorf("JoinAliasEmails returned %q, expected %q", joined, expected)
+	}
+}
+
+func TestJoinAliasEmails_Empty(t *testing.T) {
+	if _, err := JoinAliasEmails(nil); err == nil {
+		t.Errorf("JoinAliasEmails should have returned an error for an empty slice of addresses")
+	}
+}
+
+func TestJoinAliasEmails_Duplicates(t *testing.T) {
+	joined, err := JoinAliasEmails([]string{"a@foo.com", "b@foo.com", "A@Foo.com", "a@foo.com"})
+	if err != nil {
+		t.Fatalf("JoinAliasEmails returned error: %v", err)
+	}
+	if expected := "a@foo.com,b@foo.com"; joined != expected {
+		t.Errorf("JoinAliasEmails returned %q, expected %q", joined, expected)
+	}
+}
+
+func TestJoinAliasEmails_Invalid(t *testing.T) {
+	for _, addr := range []string{"", "foo", "a@foo.com,b@foo.com", "Foo <a@foo.com>"} {
+		if _, err := JoinAliasEmails([]string{"a@foo.com", addr}); err == nil {
+			t.Errorf("JoinAliasEmails should have returned an error for %q", addr)
+		}
+	}
+}
+
+func TestCanonicalizeAddresses(t *testing.T) {
+	tests := []struct {
+		name     string
+		addrs    []string
+		expected []string
+	}{
+		{
+			name:     "whitespace",
+			addrs:    []string{" a@foo.com", "b@foo.com\t"},
+			expected: []string{"a@foo.com", "b@foo.com"},
+		},
+		{
+			name:     "domain case",
+			addrs:    []string{"Alice@FOO.com"},
+			expected: []string{"Alice@foo.com"},
+		},
+		{
+			name:     "display names",
+			addrs:    []string{"Alice <a@foo.com>", `"Bob, Jr." <b@Foo.com>`},
+			expected: []string{"a@foo.com", "b@foo.com"},
+		},
+		{
+			name:     "duplicates",
+			addrs:    []string{"a@foo.com", "a@FOO.com", "Alice <a@foo.com>", "b@foo.com"},
+			expected: []string{"a@foo.com", "b@foo.com"},
+		},
+		{
+			name:     "local part case duplicates",
+			addrs:    []string{"Alice@foo.com", "alice@foo.com"},
+			expected: []string{"Alice@foo.com"},
+		},
+		{
+			name:     "empty",
+			addrs:    []string{"", "  ", "a@foo.com"},
+			expected: []string{"a@foo.com"},
+		},
+		{
+			name:     "unparseable",
+			addrs:    []string{" not an address "},
+			expected: []string{"not an address"},
+		},
+	}
+
+	for _, tt := range tests {
+		canonical := CanonicalizeAddresses(tt.addrs)
+		if !reflect.DeepEqual(canonical, tt.expected) {
+			t.Errorf("%s: CanonicalizeAddresses returned %q, expected %q", tt.name, canonical, tt.expected)
+		}
+	}
+}
+
+func TestRackspaceEmailAliases_NearMemberLimit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"aliases": [{"name":"foo","numberOfMembers":2},{"name":"bar","numberOfMembers":10},{"name":"baz","numberOfMembers":12}]}`)
+	})
+
+	aliases, _, err := client.RackspaceEmailAliases.NearMemberLimit(ctx, "domain.com", 10)
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.NearMemberLimit returned error: %v", err)
+	}
+
+	expected := []RackspaceEmailAlias{{Name: "bar", NumberOfMembers: 10}, {Name: "baz", NumberOfMembers: 12}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("RackspaceEmailAliases.NearMemberLimit returned %+v, expected %+v", aliases, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_NearMemberLimit_InvalidThreshold(t *testing.T) {
+	_, _, err := client.RackspaceEmailAliases.NearMemberLimit(ctx, "domain.com", 0)
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.NearMemberLimit should have returned an error for a zero threshold")
+	}
+}
+
+func TestRackspaceEmailAliases_ImportCSV(t *testing.T) {
+	setup()
+	defer teardown()
+
+	added := map[string]string{}
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		added[strings.TrimPrefix(r.URL.Path, "/v1/domains/foo.com/rs/aliases/")] = r.FormValue("aliasEmails")
+	})
+
+	input := "alias,addresses\n" +
+		"sales,a@foo.com;b@foo.com\n" +
+		"\n" +
+		"broken\n" +
+		"support, c@foo.com \n"
+
+	results, err := client.RackspaceEmailAliases.ImportCSV(ctx, "foo.com", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.ImportCSV returned error: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("RackspaceEmailAliases.ImportCSV returned %d results, expected 3", len(results))
+	}
+	if results[0].Name != "sales" || results[0].Line != 2 || results[0].Err != nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 0 = %+v", results[0])
+	}
+	if results[1].Line != 4 || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "line 4") {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 1 = %+v, expected an error for line 4", results[1])
+	}
+	if results[2].Name != "support" || results[2].Line != 5 || results[2].Err != nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 2 = %+v", results[2])
+	}
+
+	expected := map[string]string{"sales": "a@foo.com,b@foo.com", "support": "c@foo.com"}
+	if !reflect.DeepEqual(added, expected) {
+		t.Errorf("RackspaceEmailAliases.ImportCSV added %v, expected %v", added, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_ImportCSV_MultilineAndBOM(t *testing.T) {
+	setup()
+	defer teardown()
+
+	added := map[string]string{}
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		added[strings.TrimPrefix(r.URL.Path, "/v1/domains/foo.com/rs/aliases/")] = r.FormValue("aliasEmails")
+	})
+
+	input := "\ufeffalias,addresses\r\n" +
+		"sales,\"a@foo.com;\r\nb@foo.com\"\r\n" +
+		"broken\r\n" +
+		"support,c@foo.com\r\n"
+
+	results, err := client.RackspaceEmailAliases.ImportCSV(ctx, "foo.com", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.ImportCSV returned error: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("RackspaceEmailAliases.ImportCSV returned %d results, expected 3", len(results))
+	}
+	if results[0].Name != "sales" || results[0].Line != 2 || results[0].Err != nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 0 = %+v", results[0])
+	}
+	if results[1].Line != 4 || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "line 4") {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 1 = %+v, expected an error for line 4", results[1])
+	}
+	if results[2].Name != "support" || results[2].Line != 5 || results[2].Err != nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 2 = %+v", results[2])
+	}
+
+	expected := map[string]string{"sales": "a@foo.com,b@foo.com", "support": "c@foo.com"}
+	if !reflect.DeepEqual(added, expected) {
+		t.Errorf("RackspaceEmailAliases.ImportCSV added %v, expected %v", added, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_ImportCSV_BadQuote(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/", func(w http.ResponseWriter, r *http.Request) {})
+
+	input := "alias,addresses\n" +
+		"sa\"les,a@foo.com\n" +
+		"support,c@foo.com\n"
+
+	results, err := client.RackspaceEmailAliases.ImportCSV(ctx, "foo.com", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.ImportCSV returned error: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("RackspaceEmailAliases.ImportCSV returned %d results, expected 2", len(results))
+	}
+	if results[0].Line != 2 || results[0].Err == nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 0 = %+v, expected an error for line 2", results[0])
+	}
+	if results[1].Name != "support" || results[1].Line != 3 || results[1].Err != nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV result 1 = %+v", results[1])
+	}
+}
+
+func TestRackspaceEmailAliases_ImportCSV_BadHeader(t *testing.T) {
+	_, err := client.RackspaceEmailAliases.ImportCSV(ctx, "foo.com", strings.NewReader("name,members\nsales,a@foo.com\n"))
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.ImportCSV should have returned an error for a bad header")
+	}
+}
+
+func TestRackspaceEmailAliases_DisableEnable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	members := "baz@bar.com,qux@bar.com"
+	var calls []string
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			calls = append(calls, r.Method)
+			addrs, _ := json.Marshal(strings.Split(members, ","))
+			fmt.Fprintf(w, `{"name": "bar", "emailAddressList": {"emailAddress": %s}}`, addrs)
+		case http.MethodPut:
+			members = r.FormValue("aliasEmails")
+			calls = append(calls, r.Method+" "+members)
+		default:
+			t.Errorf("Request method = %v, the alias should not be deleted or created", r.Method)
+		}
+	})
+
+	disabled, _, err := client.RackspaceEmailAliases.Disable(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Disable returned error: %v", err)
+	}
+	if expected := []string{"baz@bar.com", "qux@bar.com"}; !reflect.DeepEqual(disabled, expected) {
+		t.Errorf("RackspaceEmailAliases.Disable returned %v, expected %v", disabled, expected)
+	}
+	if _, _, err := client.RackspaceEmailAliases.Disable(ctx, "foo.com", "bar"); err == nil {
+		t.Errorf("RackspaceEmailAliases.Disable should have returned an error for a disabled alias")
+	}
+	if _, err := client.RackspaceEmailAliases.Enable(ctx, "foo.com", "bar", nil); err != nil {
+		t.Fatalf("RackspaceEmailAliases.Enable returned error: %v", err)
+	}
+	if _, err := client.RackspaceEmailAliases.Enable(ctx, "foo.com", "bar", nil); err == nil {
+		t.Errorf("RackspaceEmailAliases.Enable should have returned an error for an enabled alias")
+	}
+
+	expected := []string{
+		http.MethodGet,
+		http.MethodPut + " " + disabledAliasPlaceholder,
+		http.MethodPut + " baz@bar.com,qux@bar.com",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("RackspaceEmailAliases.Disable/Enable made calls %v, expected %v", calls, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_Disable_AlreadyDisabled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// Disabled by another client, so only the placeholder tells.
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprintf(w, `{"name": "bar", "emailAddressList": {"emailAddress": [%q]}}`, disabledAliasPlaceholder)
+	})
+
+	if _, _, err := client.RackspaceEmailAliases.Disable(ctx, "foo.com", "bar"); err == nil {
+		t.Errorf("RackspaceEmailAliases.Disable should have returned an error for a disabled alias")
+	}
+}
+
+func TestRackspaceEmailAliases_Enable_GivenMembers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+		if v := r.FormValue("aliasEmails"); v != "baz@bar.com,qux@bar.com" {
+			t.Errorf("Request aliasEmails = %v, expected %v", v, "baz@bar.com,qux@bar.com")
+		}
+	})
+
+	// The members were persisted by the caller, e.g. before a restart, so
+	// this client has no record of the alias.
+	members := []string{"baz@bar.com", "qux@bar.com"}
+	if _, err := client.RackspaceEmailAliases.Enable(ctx, "foo.com", "bar", members); err != nil {
+		t.Fatalf("RackspaceEmailAliases.Enable returned error: %v", err)
+	}
+}
+
+func TestRackspaceEmailAliases_Disable_Failed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}}`)
+		case http.MethodPut:
+			http.Error(w, `{"message": "boom"}`, http.StatusInternalServerError)
+		}
+	})
+
+	if _, _, err := client.RackspaceEmailAliases.Disable(ctx, "foo.com", "bar"); err == nil {
+		t.Fatalf("RackspaceEmailAliases.Disable should have returned an error")
+	}
+	if _, err := client.RackspaceEmailAliases.Enable(ctx, "foo.com", "bar", nil); err == nil {
+		t.Errorf("RackspaceEmailAliases.Enable should have returned an error for an alias that was not disabled")
+	}
+}
+
+func TestSetDisabledAliasPlaceholder(t *testing.T) {
+	c, err := New(nil, SetDisabledAliasPlaceholder("devnull@foo.com"))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if c.disabledAliasPlaceholder != "devnull@foo.com" {
+		t.Errorf("disabledAliasPlaceholder = %q, expected %q", c.disabledAliasPlaceholder, "devnull@foo.com")
+	}
+
+	if _, err := New(nil, SetDisabledAliasPlaceholder("devnull")); err == nil {
+		t.Errorf("SetDisabledAliasPlaceholder should have returned an error for an invalid address")
+	}
+}
+
+func TestRackspaceEmailAliases_AddressMap(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"aliases": [{"name":"sales"},{"name":"support"}]}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/sales", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "sales", "emailAddressList": {"emailAddress": ["a@foo.com", "b@foo.com"]}}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/support", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "support", "emailAddressList": {"emailAddress": ["c@foo.com"]}}`)
+	})
+
+	addresses, _, err := client.RackspaceEmailAliases.AddressMap(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.AddressMap returned error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"sales":   {"a@foo.com", "b@foo.com"},
+		"support": {"c@foo.com"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("RackspaceEmailAliases.AddressMap returned %v, expected %v", addresses, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_ShowMany(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/sales", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name": "sales", "emailAddressList": {"emailAddress": ["a@foo.com"]}}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/support", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name": "support", "emailAddressList": {"emailAddress": ["b@foo.com", "c@foo.com"]}}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Alias not found"}`)
+	})
+
+	results, errs := client.RackspaceEmailAliases.ShowMany(ctx, "foo.com", []string{"sales", "missing", "support", "sales"}, 2)
+
+	expected := map[string]*RackspaceEmailAliasShow{
+		"sales":   {Name: "sales", EmailAddressList: EmailAddress{Addresses: []string{"a@foo.com"}}},
+		"support": {Name: "support", EmailAddressList: EmailAddress{Addresses: []string{"b@foo.com", "c@foo.com"}}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("RackspaceEmailAliases.ShowMany returned %+v, expected %+v", results, expected)
+	}
+
+	if len(errs) != 1 || !isNotFound(errs["missing"]) {
+		t.Errorf("RackspaceEmailAliases.ShowMany errors = %v, expected a not found error for missing", errs)
+	}
+}
+
+func TestRackspaceEmailAliases_ShowMany_Cancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	results, errs := client.RackspaceEmailAliases.ShowMany(cctx, "foo.com", []string{"sales", "support"}, 2)
+	if len(results) != 0 {
+		t.Errorf("RackspaceEmailAliases.ShowMany returned %v, expected no results", results)
+	}
+	for _, name := range []string{"sales", "support"} {
+		if !errors.Is(errs[name], context.Canceled) {
+			t.Errorf("RackspaceEmailAliases.ShowMany error for %s = %v, expected %v", name, errs[name], context.Canceled)
+		}
+	}
+}
+
+func TestRackspaceEmailAliases_ExportNDJSON(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"aliases": [{"name":"sales"},{"name":"support"}]}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/sales", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "sales", "emailAddressList": {"emailAddress": ["a@foo.com", "b@foo.com"]}}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/support", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "support", "emailAddressList": {"emailAddress": []}}`)
+	})
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if err := client.RackspaceEmailAliases.ExportNDJSON(ctx, "foo.com", w); err != nil {
+		t.Fatalf("RackspaceEmailAliases.ExportNDJSON returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []aliasExport{
+		{Name: "sales", Members: []string{"a@foo.com", "b@foo.com"}},
+		{Name: "support", Members: []string{}},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("RackspaceEmailAliases.ExportNDJSON wrote %d lines, expected %d:\n%s", len(lines), len(expected), buf.String())
+	}
+	for i, line := range lines {
+		var got aliasExport
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("line %d = %+v, expected %+v", i+1, got, expected[i])
+		}
+	}
+}
+
+func TestRackspaceEmailAliases_Show_NotFoundNil(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetShowNotFoundNil(true)(client); err != nil {
+		t.Fatalf("SetShowNotFoundNil(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Alias not found"}`)
+	})
+
+	alias, resp, err := client.RackspaceEmailAliases.Show(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Show returned error: %v", err)
+	}
+	if alias != nil {
+		t.Errorf("RackspaceEmailAliases.Show returned %+v, expected nil", alias)
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("RackspaceEmailAliases.Show should have returned the 404 response")
+	}
+
+	// Methods built on Show still see the missing alias as an error.
+	if _, err := client.RackspaceEmailAliases.Rename(ctx, "foo.com", "bar", "baz"); !isNotFound(err) {
+		t.Errorf("RackspaceEmailAliases.Rename returned %v, expected a not found error", err)
+	}
+}
+
+func TestRackspaceEmailAliases_IndexWithTotal(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	responses := []string{
+		`{"offset": 0, "size": 2, "total": 3, "aliases": [{"name":"foo"},{"name":"bar"}]}`,
+		`{"offset": 2, "size": 2, "total": 3, "aliases": [{"name":"baz"}]}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	aliases, total, _, err := client.RackspaceEmailAliases.IndexWithTotal(ctx, &PageOptions{Size: 2}, "foo.com")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.IndexWithTotal returned error: %v", err)
+	}
+
+	expected := []RackspaceEmailAlias{{Name: "foo"}, {Name: "bar"}, {Name: "baz"}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("RackspaceEmailAliases.IndexWithTotal returned %+v, expected %+v", aliases, expected)
+	}
+	if total != 3 {
+		t.Errorf("RackspaceEmailAliases.IndexWithTotal total = %d, expected %d", total, 3)
+	}
+}
+
+func TestVerifyAliasCount(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"offset": 0, "size": 50, "total": 2, "aliases": [{"name":"foo"},{"name":"bar"}]}`)
+	})
+
+	if err := client.VerifyAliasCount(ctx, "foo.com"); err != nil {
+		t.Errorf("VerifyAliasCount returned error: %v", err)
+	}
+}
+
+func TestVerifyAliasCount_Mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	// The second page is short by one alias.
+	responses := []string{
+		`{"offset": 0, "size": 2, "total": 4, "aliases": [{"name":"foo"},{"name":"bar"}]}`,
+		`{"offset": 2, "size": 2, "total": 4, "aliases": [{"name":"baz"}]}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	err := client.VerifyAliasCount(ctx, "foo.com")
+	if err == nil {
+		t.Fatalf("VerifyAliasCount should have returned an error for a count mismatch")
+	}
+	if !strings.Contains(err.Error(), "received 3 aliases, the API reported 4") {
+		t.Errorf("VerifyAliasCount error = %q, expected it to report both counts", err)
+	}
+}
+
+func TestRackspaceEmailAliases_DeleteAll(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"aliases": [{"name":"sales"},{"name":"support"}]}`)
+	})
+
+	var deleted []string
+	for _, name := range []string{"sales", "support"} {
+		name := name
+		mux.HandleFunc("/v1/domains/foo.com/rs/aliases/"+name, func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodDelete)
+			deleted = append(deleted, name)
+		})
+	}
+
+	n, err := client.RackspaceEmailAliases.DeleteAll(ctx, "foo.com", "foo.com")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.DeleteAll returned error: %v", err)
+	}
+
+	if n != 2 {
+		t.Errorf("RackspaceEmailAliases.DeleteAll returned %d, expected %d", n, 2)
+	}
+	expected := []string{"sales", "support"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("RackspaceEmailAliases.DeleteAll deleted %v, expected %v", deleted, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_DeleteAll_WrongConfirmation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No request should have been sent, got %s %s", r.Method, r.URL)
+	})
+
+	for _, confirm := range []string{"", "bar.com", "FOO.COM"} {
+		n, err := client.RackspaceEmailAliases.DeleteAll(ctx, "foo.com", confirm)
+		if err == nil {
+			t.Errorf("RackspaceEmailAliases.DeleteAll should have returned an error for confirmation %q", confirm)
+		}
+		if n != 0 {
+			t.Errorf("RackspaceEmailAliases.DeleteAll returned %d, expected %d", n, 0)
+		}
+	}
+}
+
+func TestRackspaceEmailAliases_Count(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if size := r.URL.Query().Get("size"); size != "1" {
+			t.Errorf("Request size = %q, expected %q", size, "1")
+		}
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 7, "aliases": [{"name":"foo"}]}`)
+	})
+
+	count, _, err := client.RackspaceEmailAliases.Count(ctx, "domain.com")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Count returned error: %v", err)
+	}
+
+	if count != 7 {
+		t.Errorf("RackspaceEmailAliases.Count returned %d, expected %d", count, 7)
+	}
+}
+
+func TestRackspaceEmailAliases_Count_DomainEmpty(t *testing.T) {
+	setup()
+	defer teardown()
+
+	_, _, err := client.RackspaceEmailAliases.Count(ctx, "")
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.Count should have returned an error for an empty domain")
+	}
+}
+
+func TestRackspaceEmailAliases_Index_SinglePage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetAutoPaginate(false)(client); err != nil {
+		t.Fatalf("SetAutoPaginate(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		if offset := r.URL.Query().Get("offset"); offset != "1" {
+			t.Errorf("Request offset = %q, expected %q", offset, "1")
+		}
+		fmt.Fprint(w, `{"offset": 1, "size": 1, "total": 3, "aliases": [{"name":"bar"}]}`)
+	})
+
+	aliases, _, err := client.RackspaceEmailAliases.Index(ctx, &PageOptions{Offset: 1, Size: 1}, "domain.com")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Index returned error: %v", err)
+	}
+
+	expected := []RackspaceEmailAlias{{Name: "bar"}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("RackspaceEmailAliases.Index returned %+v, expected %+v", aliases, expected)
+	}
+}
+
+func TestRackspaceEmailAliases_DeleteAll_AutoPaginateDisabled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Inf, 1)
+	if err := SetAutoPaginate(false)(client); err != nil {
+		t.Fatalf("SetAutoPaginate(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if r.URL.Query().Get("offset") == "1" {
+			fmt.Fprint(w, `{"offset": 1, "size": 1, "total": 2, "aliases": [{"name":"support"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 2, "aliases": [{"name":"sales"}]}`)
+	})
+
+	var deleted []string
+	for _, name := range []string{"sales", "support"} {
+		name := name
+		mux.HandleFunc("/v1/domains/foo.com/rs/aliases/"+name, func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodDelete)
+			deleted = append(deleted, name)
+		})
+	}
+
+	n, err := client.RackspaceEmailAliases.DeleteAll(ctx, "foo.com", "foo.com")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.DeleteAll returned error: %v", err)
+	}
+
+	expected := []string{"sales", "support"}
+	if n != 2 || !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("RackspaceEmailAliases.DeleteAll deleted %d %v, expected %d %v", n, deleted, 2, expected)
+	}
+
+	if err := client.VerifyAliasCount(ctx, "foo.com"); err != nil {
+		t.Errorf("VerifyAliasCount returned error: %v", err)
+	}
+}
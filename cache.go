@@ -0,0 +1,130 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a successful GET response kept by a responseCache.
+type cacheEntry struct {
+	resp    *http.Response
+	body    []byte
+	expires time.Time
+}
+
+// response returns a copy of the cached response for req with its own body
+// reader.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	resp := *e.resp
+	resp.Request = req
+	resp.Body = ioutil.NopCloser(bytes.NewReader(e.body))
+	return &resp
+}
+
+// responseCache keeps successful GET responses for a fixed time to live.
+type responseCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// do returns a copy of the response cached for key if it has not expired at
+// now. Otherwise it calls fn and caches its response if it was successful.
+// The body of a cached response is read and closed by do.
+func (rc *responseCache) do(key string, req *http.Request, now time.Time, fn func() (*http.Response, error)) (*http.Response, error) {
+	rc.mu.Lock()
+	e, ok := rc.entries[key]
+	if ok && now.Before(e.expires) {
+		rc.mu.Unlock()
+		return e.response(req), nil
+	}
+	delete(rc.entries, key)
+	rc.mu.Unlock()
+
+	resp, err := fn()
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	e = &cacheEntry{resp: resp, body: body, expires: now.Add(rc.ttl)}
+	rc.mu.Lock()
+	rc.evictExpired(now)
+	rc.entries[key] = e
+	rc.mu.Unlock()
+
+	return e.response(req), nil
+}
+
+// evictExpired removes the entries that have expired at now, so that keys
+// which are never requested again do not pile up. rc.mu must be held.
+func (rc *responseCache) evictExpired(now time.Time) {
+	for key, e := range rc.entries {
+		if !now.Before(e.expires) {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// WithCacheBypass returns a copy of ctx that makes GETs sent with it skip the
+// cache set up with SetCache, neither reading from it nor storing in it.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassContextKey, true)
+}
+
+// SetCache is a client option for caching successful GET responses for ttl,
+// keyed on the URL and Accept header. Cached responses are served without a
+// request, so they can be up to ttl out of date, including after changes made
+// through the same client. Use WithCacheBypass for calls that need fresh data;
+// WaitForJob and the helpers that decide what to change from what they read,
+// such as AddIfNotExists, Rename, Disable and DeleteAll, always bypass the
+// cache. Expired entries are dropped whenever a new response is cached. A ttl
+// of zero disables the cache.
+func SetCache(ttl time.Duration) func(*Client) error {
+	return func(c *Client) error {
+		if ttl < 0 {
+			return NewArgError("ttl", "it cannot be negative")
+		}
+
+		if ttl > 0 {
+			c.cache = newResponseCache(ttl)
+		} else {
+			c.cache = nil
+		}
+		return nil
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func setupCache(t *testing.T, ttl time.Duration) *fakeClock {
+	clock := newFakeClock()
+	if err := SetClock(clock)(client); err != nil {
+		t.Fatalf("SetClock(): %v", err)
+	}
+	if err := SetCache(ttl)(client); err != nil {
+		t.Fatalf("SetCache(): %v", err)
+	}
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+	return clock
+}
+
+func TestSetCache_Hit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := setupCache(t, time.Minute)
+
+	requests := 0
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"domain": {"name":"foo.com","activeSyncLicenses":%d}}`, requests)
+	})
+
+	first, _, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	clock.Sleep(30 * time.Second)
+
+	second, _, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Server received %d requests, expected %d", requests, 1)
+	}
+	if second.ActiveSyncLicenses != first.ActiveSyncLicenses {
+		t.Errorf("Domains.Show returned %+v, expected the cached %+v", second, first)
+	}
+}
+
+func TestSetCache_Expired(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := setupCache(t, time.Minute)
+
+	requests := 0
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"domain": {"name":"foo.com","activeSyncLicenses":%d}}`, requests)
+	})
+
+	if _, _, err := client.Domains.Show(ctx, "foo.com"); err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	clock.Sleep(time.Minute)
+
+	domain, _, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Server received %d requests, expected %d", requests, 2)
+	}
+	if domain.ActiveSyncLicenses != 2 {
+		t.Errorf("Domains.Show returned %+v, expected the fresh response", domain)
+	}
+}
+
+func TestSetCache_Bypass(t *testing.T) {
+	setup()
+	defer teardown()
+
+	setupCache(t, time.Minute)
+
+	requests := 0
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Domains.Show(WithCacheBypass(ctx), "foo.com"); err != nil {
+			t.Fatalf("Domains.Show returned error: %v", err)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Server received %d requests, expected %d", requests, 2)
+	}
+}
+
+func TestSetCache_ErrorsNotCached(t *testing.T) {
+	setup()
+	defer teardown()
+
+	setupCache(t, time.Minute)
+
+	requests := 0
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Domain not found"}`)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Domains.Show(ctx, "foo.com"); !isNotFound(err) {
+			t.Fatalf("Domains.Show returned %v, expected a not found error", err)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Server received %d requests, expected %d", requests, 2)
+	}
+}
+
+func TestSetCache_Concurrent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	setupCache(t, time.Minute)
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			domain, _, err := client.Domains.Show(ctx, "foo.com")
+			if err != nil || domain.Name != "foo.com" {
+				t.Errorf("Domains.Show returned %+v, %v", domain, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSetCache_Invalid(t *testing.T) {
+	if _, err := New(nil, SetCache(-time.Second)); err == nil {
+		t.Errorf("New() should have returned an error for a negative ttl")
+	}
+}
+
+func TestSetCache_EvictsExpired(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := setupCache(t, time.Minute)
+
+	mux.HandleFunc("/v1/domains/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	for _, name := range []string{"a.com", "b.com", "c.com"} {
+		if _, _, err := client.Domains.Show(ctx, name); err != nil {
+			t.Fatalf("Domains.Show returned error: %v", err)
+		}
+	}
+
+	clock.Sleep(2 * time.Minute)
+
+	if _, _, err := client.Domains.Show(ctx, "d.com"); err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	client.cache.mu.Lock()
+	n := len(client.cache.entries)
+	client.cache.mu.Unlock()
+	if n != 1 {
+		t.Errorf("Cache holds %d entries, expected only the unexpired %d", n, 1)
+	}
+}
+
+func TestSetCache_AddIfNotExistsBypass(t *testing.T) {
+	setup()
+	defer teardown()
+
+	setupCache(t, time.Hour)
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	exists := false
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if !exists {
+				http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `{"name": "bar"}`)
+		case http.MethodPost:
+			exists = true
+		}
+	})
+
+	if _, err := client.RackspaceEmailAliases.AddIfNotExists(ctx, "foo.com", "bar", []string{"a@foo.com"}); err != nil {
+		t.Fatalf("RackspaceEmailAliases.AddIfNotExists returned error: %v", err)
+	}
+	// Prime the cache with the alias as it is now.
+	if _, _, err := client.RackspaceEmailAliases.Show(ctx, "foo.com", "bar"); err != nil {
+		t.Fatalf("RackspaceEmailAliases.Show returned error: %v", err)
+	}
+
+	exists = false
+	if _, err := client.RackspaceEmailAliases.AddIfNotExists(ctx, "foo.com", "bar", []string{"a@foo.com"}); err != nil {
+		t.Errorf("RackspaceEmailAliases.AddIfNotExists should not have used the cached alias, got %v", err)
+	}
+}
+
+func TestSetCache_DeleteAllBypass(t *testing.T) {
+	setup()
+	defer teardown()
+
+	setupCache(t, time.Hour)
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	aliases := []string{"a"}
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		for _, name := range aliases {
+			names = append(names, fmt.Sprintf(`{"name":%q}`, name))
+		}
+		fmt.Fprintf(w, `{"aliases": [%s]}`, strings.Join(names, ","))
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		name := strings.TrimPrefix(r.URL.Path, "/v1/domains/foo.com/rs/aliases/")
+		for i, a := range aliases {
+			if a == name {
+				aliases = append(aliases[:i], aliases[i+1:]...)
+				return
+			}
+		}
+		http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+	})
+
+	if n, err := client.RackspaceEmailAliases.DeleteAll(ctx, "foo.com", "foo.com"); err != nil || n != 1 {
+		t.Fatalf("RackspaceEmailAliases.DeleteAll returned %d, %v, expected 1, nil", n, err)
+	}
+
+	// An alias created since must be seen, and the deleted one must not.
+	aliases = []string{"b"}
+	if n, err := client.RackspaceEmailAliases.DeleteAll(ctx, "foo.com", "foo.com"); err != nil || n != 1 {
+		t.Errorf("RackspaceEmailAliases.DeleteAll returned %d, %v, expected 1, nil", n, err)
+	}
+	if len(aliases) != 0 {
+		t.Errorf("RackspaceEmailAliases.DeleteAll left %v", aliases)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when it is slept on or waited for.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Sleep(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func Test_New_OptionSetClock_Nil(t *testing.T) {
+	_, err := New(nil, SetClock(nil))
+	if err == nil {
+		t.Errorf("New() should have returned an error for a nil clock")
+	}
+}
+
+func TestClock_Sign(t *testing.T) {
+	c, err := New(nil, SetUserKey("userid"), SetSecretKey("hunter2"), SetClock(newFakeClock()))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	req, err := c.NewRequest(ctx, http.MethodGet, "v1/domains", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+
+	sig := req.Header.Get("X-Api-Signature")
+	if !strings.HasPrefix(sig, "userid:20200102030405:") {
+		t.Errorf("X-Api-Signature = %q, expected the fake clock's timestamp", sig)
+	}
+}
+
+func TestClock_SignUTC(t *testing.T) {
+	clock := newFakeClock()
+	clock.now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+
+	c, err := New(nil, SetUserKey("userid"), SetSecretKey("hunter2"), SetClock(clock))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	req, err := c.NewRequest(ctx, http.MethodGet, "v1/domains", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+
+	sig := req.Header.Get("X-Api-Signature")
+	if !strings.HasPrefix(sig, "userid:20200102080405:") {
+		t.Errorf("X-Api-Signature = %q, expected the timestamp in UTC", sig)
+	}
+}
+
+func TestClock_WaitForJob(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := newFakeClock()
+	client.clock = clock
+
+	responses := []string{
+		`{"id": "1", "state": "inProgress"}`,
+		`{"id": "1", "state": "inProgress"}`,
+		`{"id": "1", "state": "completed"}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	_, err := client.WaitForJob(ctx, "v1/jobs/1", time.Hour)
+	if err != nil {
+		t.Fatalf("WaitForJob returned error: %v", err)
+	}
+
+	expected := []time.Duration{time.Hour, time.Hour}
+	if !reflect.DeepEqual(clock.waits, expected) {
+		t.Errorf("WaitForJob waited %v, expected %v", clock.waits, expected)
+	}
+}
+
+func TestClock_RetryAfterDate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := newFakeClock()
+	client.clock = clock
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", clock.Now().Add(90*time.Second).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html>maintenance</html>")
+	})
+
+	_, _, err := client.Domains.Show(ctx, "foo.com")
+	unavailable, ok := err.(*ServiceUnavailableError)
+	if !ok {
+		t.Fatalf("Domains.Show returned %v, expected a *ServiceUnavailableError", err)
+	}
+	if unavailable.RetryAfter != 90*time.Second {
+		t.Errorf("RetryAfter = %v, expected %v", unavailable.RetryAfter, 90*time.Second)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// flightCall is an in-flight or completed request shared by a flightGroup.
+type flightCall struct {
+	wg   sync.WaitGroup
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// response returns a copy of the shared response with its own body reader.
+func (fc *flightCall) response() (*http.Response, error) {
+	if fc.err != nil {
+		return nil, fc.err
+	}
+
+	resp := *fc.resp
+	resp.Body = ioutil.NopCloser(bytes.NewReader(fc.body))
+	return &resp, nil
+}
+
+// flightGroup collapses concurrent requests with the same key into a single
+// request whose response is shared by all callers.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do calls fn unless a call for key is already in flight, in which case it
+// waits for that call and returns a copy of its response instead. The body of
+// the response is read and closed by do.
+func (g *flightGroup) do(key string, fn func() (*http.Response, error)) (*http.Response, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if fc, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		fc.wg.Wait()
+		return fc.response()
+	}
+
+	fc := new(flightCall)
+	fc.wg.Add(1)
+	g.calls[key] = fc
+	g.mu.Unlock()
+
+	fc.resp, fc.err = fn()
+	if fc.err == nil {
+		fc.body, fc.err = ioutil.ReadAll(fc.resp.Body)
+		fc.resp.Body.Close()
+	}
+	fc.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return fc.response()
+}
+
+// SetCoalesceGETs is a client option for sharing one request between
+// concurrent identical GETs, keyed on the URL and Accept header. The callers
+// that join an in-flight request get its result, including any error caused
+// by the context of the caller that started it.
+func SetCoalesceGETs(coalesce bool) func(*Client) error {
+	return func(c *Client) error {
+		if coalesce {
+			c.getGroup = new(flightGroup)
+		} else {
+			c.getGroup = nil
+		}
+		return nil
+	}
+}
+
+// mutationKey identifies the mutating requests that can share a response by
+// their method, URL and a hash of their body.
+func mutationKey(req *http.Request) (string, error) {
+	hasher := sha256.New()
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		if _, err := io.Copy(hasher, body); err != nil {
+			return "", err
+		}
+	}
+
+	return req.Method + " " + req.URL.String() + "\n" + hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// SetDedupeMutations is a client option for sharing one request between
+// concurrent identical POST, PUT and DELETE requests, keyed on the method, URL
+// and body. It keeps callers that race to make the same change, e.g. retries
+// from different goroutines, from creating duplicates. Only requests in
+// flight at the same time are shared; a later identical request is sent
+// again.
+func SetDedupeMutations(dedupe bool) func(*Client) error {
+	return func(c *Client) error {
+		if dedupe {
+			c.mutationGroup = new(flightGroup)
+		} else {
+			c.mutationGroup = nil
+		}
+		return nil
+	}
+}
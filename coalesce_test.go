@@ -0,0 +1,145 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSetCoalesceGETs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetCoalesceGETs(true)(client); err != nil {
+		t.Fatalf("SetCoalesceGETs(): %v", err)
+	}
+
+	var requests int32
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}}`)
+	})
+
+	var wg sync.WaitGroup
+	callers := 5
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			alias, _, err := client.RackspaceEmailAliases.Show(ctx, "foo.com", "bar")
+			if err != nil {
+				t.Errorf("RackspaceEmailAliases.Show returned error: %v", err)
+				return
+			}
+			if alias.Name != "bar" || len(alias.EmailAddressList.Addresses) != 1 {
+				t.Errorf("RackspaceEmailAliases.Show returned %+v", alias)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Server received %d requests, expected 1", n)
+	}
+}
+
+func TestSetCoalesceGETs_Disabled(t *testing.T) {
+	c, err := New(nil, SetCoalesceGETs(true), SetCoalesceGETs(false))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.getGroup != nil {
+		t.Errorf("SetCoalesceGETs(false) should disable coalescing")
+	}
+}
+
+func TestSetDedupeMutations(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetDedupeMutations(true)(client); err != nil {
+		t.Fatalf("SetDedupeMutations(): %v", err)
+	}
+
+	var requests int32
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{"baz@bar.com"}); err != nil {
+				t.Errorf("RackspaceEmailAliases.Add returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Server received %d requests, expected 1", n)
+	}
+}
+
+func TestSetDedupeMutations_DifferentBodies(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetDedupeMutations(true)(client); err != nil {
+		t.Fatalf("SetDedupeMutations(): %v", err)
+	}
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	var requests int32
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	var wg sync.WaitGroup
+	for _, addr := range []string{"a@bar.com", "b@bar.com"} {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if _, err := client.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{addr}); err != nil {
+				t.Errorf("RackspaceEmailAliases.Add returned error: %v", err)
+			}
+		}(addr)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Server received %d requests, expected 2", n)
+	}
+}
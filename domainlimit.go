@@ -0,0 +1,107 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// domainLimiters holds a rate limiter for each domain that requests were made
+// for.
+type domainLimiters struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newDomainLimiters(rps float64, burst int) *domainLimiters {
+	return &domainLimiters{
+		limit:    rate.Limit(rps),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// get returns the limiter for domain, creating it on first use.
+func (d *domainLimiters) get(domain string) *rate.Limiter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l, ok := d.limiters[domain]
+	if !ok {
+		l = rate.NewLimiter(d.limit, d.burst)
+		d.limiters[domain] = l
+	}
+	return l
+}
+
+// requestDomain returns the domain a request is for, taken from the segment
+// after "domains" in its path, or an empty string if it is not for a single
+// domain.
+func requestDomain(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] == "domains" {
+			return strings.ToLower(segments[i+1])
+		}
+	}
+	return ""
+}
+
+// domainLimiter returns the limiter of the domain req is for, or nil if
+// SetPerDomainRateLimit is not enabled or req is not for a single domain.
+func (c *Client) domainLimiter(req *http.Request) *rate.Limiter {
+	if c.domainLimiters == nil {
+		return nil
+	}
+	if domain := requestDomain(req); domain != "" {
+		return c.domainLimiters.get(domain)
+	}
+	return nil
+}
+
+// SetPerDomainRateLimit is a client option for rate limiting the requests for
+// each domain separately, so that heavy activity on one domain does not
+// starve the others. Each domain gets its own limiter, shared by all methods,
+// allowing rps requests per second with bursts of burst requests. The domain
+// limiters come in addition to the limiters set with SetGetLimiter and
+// SetPostLimiter, which every request still waits on since the API's own
+// limits apply to the whole account. AllowGET and AllowMutate only check the
+// latter.
+func SetPerDomainRateLimit(rps float64, burst int) func(*Client) error {
+	return func(c *Client) error {
+		if rps <= 0 {
+			return NewArgError("rps", "it must be greater than zero")
+		}
+		if burst < 1 {
+			return NewArgError("burst", "it must be greater than zero")
+		}
+
+		c.domainLimiters = newDomainLimiters(rps, burst)
+		return nil
+	}
+}
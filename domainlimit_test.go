@@ -0,0 +1,127 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSetPerDomainRateLimit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetPerDomainRateLimit(1.0/3600, 1)(client); err != nil {
+		t.Fatalf("SetPerDomainRateLimit(): %v", err)
+	}
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	for _, name := range []string{"foo.com", "bar.com"} {
+		name := name
+		mux.HandleFunc("/v1/domains/"+name, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"domain": {"name":%q}}`, name)
+		})
+	}
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domains": [{"name":"foo.com"},{"name":"bar.com"}]}`)
+	})
+
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	// Each domain has its own token.
+	for _, name := range []string{"foo.com", "bar.com"} {
+		if _, _, err := client.Domains.Show(tctx, name); err != nil {
+			t.Fatalf("Domains.Show(%s) returned error: %v", name, err)
+		}
+	}
+
+	// The token of foo.com has been used up.
+	if _, _, err := client.Domains.Show(tctx, "foo.com"); err == nil || !strings.HasPrefix(err.Error(), "rate limiter wait: ") {
+		t.Errorf("Domains.Show returned %v, expected to be throttled", err)
+	}
+
+	// Requests that are not for a domain use the global limiter.
+	if _, _, err := client.Domains.Index(tctx, nil); err != nil {
+		t.Errorf("Domains.Index returned error: %v", err)
+	}
+}
+
+func TestSetPerDomainRateLimit_GlobalLimit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetPerDomainRateLimit(100, 1)(client); err != nil {
+		t.Fatalf("SetPerDomainRateLimit(): %v", err)
+	}
+	client.getLimiter = rate.NewLimiter(1.0/3600, 1)
+
+	for _, name := range []string{"foo.com", "bar.com"} {
+		name := name
+		mux.HandleFunc("/v1/domains/"+name, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"domain": {"name":%q}}`, name)
+		})
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if _, _, err := client.Domains.Show(tctx, "foo.com"); err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	// bar.com has a token of its own, but the account wide one is used up.
+	if _, _, err := client.Domains.Show(tctx, "bar.com"); err == nil || !strings.HasPrefix(err.Error(), "rate limiter wait: ") {
+		t.Errorf("Domains.Show returned %v, expected to be throttled", err)
+	}
+}
+
+func TestSetPerDomainRateLimit_Invalid(t *testing.T) {
+	if _, err := New(nil, SetPerDomainRateLimit(0, 1)); err == nil {
+		t.Errorf("New() should have returned an error for a zero rps")
+	}
+	if _, err := New(nil, SetPerDomainRateLimit(1, 0)); err == nil {
+		t.Errorf("New() should have returned an error for a zero burst")
+	}
+}
+
+func TestRequestDomain(t *testing.T) {
+	tests := map[string]string{
+		"https://api.emailsrvr.com/v1/domains":                       "",
+		"https://api.emailsrvr.com/v1/domains/Foo.com":               "foo.com",
+		"https://api.emailsrvr.com/v1/domains/foo.com/rs/aliases/ab": "foo.com",
+		"https://api.emailsrvr.com/v1/customers/me/domains/bar.com":  "bar.com",
+		"https://api.emailsrvr.com/v1/jobs/123":                      "",
+	}
+
+	for u, expected := range tests {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		if domain := requestDomain(req); domain != expected {
+			t.Errorf("requestDomain(%s) = %q, expected %q", u, domain, expected)
+		}
+	}
+}
@@ -22,8 +22,11 @@ package reago
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 )
 
 const domainsBasePath = "v1/domains"
@@ -34,18 +37,28 @@ const domainsBasePath = "v1/domains"
 // See: http://api-wiki.apps.rackspace.com/api-wiki/index.php?title=Domain_(Rest_API)
 type DomainsService interface {
 	Index(context.Context, *PageOptions) ([]Domain, *Response, error)
+	IndexWithTotal(context.Context, *PageOptions) ([]Domain, int, *Response, error)
 	Show(context.Context, string) (*Domain, *Response, error)
+	ShowWithRaw(context.Context, string) (*Domain, json.RawMessage, *Response, error)
+	SetArchiving(context.Context, string, bool) (*Response, error)
+	SetActiveSync(context.Context, string, bool) (*Response, error)
+	SetBlackBerry(context.Context, string, bool) (*Response, error)
 }
 
 // DomainsServiceOp handles communication with the domain related methods of
 // the Rackspace Email API.
 type DomainsServiceOp struct {
-	client *Client
+	client   *Client
+	basePath string
 }
 
 var _ DomainsService = DomainsServiceOp{}
 var _ DomainsService = &DomainsServiceOp{}
 
+func (s *DomainsServiceOp) setBasePath(path string) {
+	s.basePath = path
+}
+
 // Domain represents a Rackspace Email API domain
 type Domain struct {
 	Name                           string `json:"name"`
@@ -63,6 +76,25 @@ type Domain struct {
 	RSEmailExtraStorage            int    `json:"rsEmailExtraStorage"`
 	RSEmailMaxNumberMailboxes      int    `json:"rsEmailMaxNumberMailboxes"`
 	RSEmailUsedStorage             int    `json:"rsEmailUsedStorage"`
+
+	// Extra holds the fields returned by the API that are not modeled above
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a domain, keeping unmodeled fields in Extra.
+func (d *Domain) UnmarshalJSON(data []byte) error {
+	type domain Domain
+	if err := json.Unmarshal(data, (*domain)(d)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, d)
+	if err != nil {
+		return err
+	}
+	d.Extra = extra
+
+	return nil
 }
 
 type domainRoot struct {
@@ -70,68 +102,319 @@ type domainRoot struct {
 }
 
 type domainsRoot struct {
-	Offset  int      `struct:"offset"`
-	Size    int      `struct:"size"`
-	Total   int      `struct:"total"`
+	page
 	Domains []Domain `json:"domains"`
 }
 
-// Index lists all domains
+// Index lists all domains. If fetching a page fails, the domains from the
+// pages fetched before it are returned along with the error.
 func (s DomainsServiceOp) Index(ctx context.Context, opt *PageOptions) ([]Domain, *Response, error) {
+	domains, _, resp, err := s.index(ctx, opt)
+	return domains, resp, err
+}
+
+// IndexWithTotal lists all domains like Index and also returns the total
+// number of domains reported by the API on the last page fetched. The total
+// can differ from the number of domains returned if domains were added or
+// removed while paging.
+func (s DomainsServiceOp) IndexWithTotal(ctx context.Context, opt *PageOptions) ([]Domain, int, *Response, error) {
+	return s.index(ctx, opt)
+}
+
+func (s DomainsServiceOp) index(ctx context.Context, opt *PageOptions) ([]Domain, int, *Response, error) {
 	var domains []Domain
+	total, resp, err := s.pages(ctx, opt, func(page []Domain) error {
+		domains = append(domains, page...)
+		return nil
+	})
+	return domains, total, resp, err
+}
+
+// pages fetches the domains one page at a time and calls fn with each page,
+// stopping at the first error from fetching a page or from fn. It returns the
+// total reported by the API on the last page fetched.
+func (s DomainsServiceOp) pages(ctx context.Context, opt *PageOptions, fn func([]Domain) error) (int, *Response, error) {
+	var total int
 	var resp *Response
 	var err error
 
 	if opt == nil {
 		opt = &PageOptions{Size: defaultPageSize}
 	}
+	if opt.Size == 0 {
+		opt.Size = defaultPageSize
+	}
+	if opt.Size > maxPageSize {
+		return 0, nil, NewArgError("opt.Size", fmt.Sprintf("it cannot be larger than %d", maxPageSize))
+	}
 
 	for {
-		path := domainsBasePath
+		path := s.basePath
 		path, err := addOptions(path, opt)
 		if err != nil {
-			return nil, nil, err
+			return total, resp, err
 		}
 
 		req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
 		if err != nil {
-			return nil, nil, err
+			return total, resp, err
 		}
 
 		root := new(domainsRoot)
 		resp, err = s.client.Do(ctx, req, root)
 		if err != nil {
-			return nil, resp, err
+			return total, resp, err
+		}
+		total = root.Total
+		if err := fn(root.Domains); err != nil {
+			return total, resp, err
 		}
-		domains = append(domains, root.Domains...)
 
-		if root.Total <= root.Size+root.Offset {
+		if s.client.onePage(ctx) {
 			break
 		}
-		opt.Offset = root.Size + root.Offset
+
+		if s.client.pagePrefetch > 1 {
+			if offsets, ok := s.client.remainingOffsets(root.page); ok {
+				resp, err = s.prefetchPages(ctx, *opt, offsets, resp, fn)
+				return total, resp, err
+			}
+		}
+
+		next, more := s.client.advance(root.page)
+		if !more {
+			break
+		}
+		opt.Offset = next
 	}
 
-	return domains, resp, err
+	return total, resp, err
+}
+
+// prefetchPages fetches the pages at offsets concurrently and calls fn with
+// each of them in order, up to the first page that failed. resp is returned
+// unless a later page was passed to fn.
+func (s DomainsServiceOp) prefetchPages(ctx context.Context, opt PageOptions, offsets []int, resp *Response, fn func([]Domain) error) (*Response, error) {
+	roots := make([]*domainsRoot, len(offsets))
+	resps := make([]*Response, len(offsets))
+
+	err := s.client.prefetch(ctx, offsets, func(ctx context.Context, i, offset int) error {
+		opt := opt
+		opt.Offset = offset
+		path, err := addOptions(s.basePath, &opt)
+		if err != nil {
+			return err
+		}
+
+		req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+
+		root := new(domainsRoot)
+		resps[i], err = s.client.Do(ctx, req, root)
+		if err != nil {
+			return err
+		}
+		roots[i] = root
+		return nil
+	})
+
+	for i, root := range roots {
+		if root == nil {
+			break
+		}
+		resp = resps[i]
+		if err := fn(root.Domains); err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, err
+}
+
+// domainPager is implemented by DomainsServiceOp to list domains one page at a
+// time.
+type domainPager interface {
+	pages(context.Context, *PageOptions, func([]Domain) error) (int, *Response, error)
+}
+
+var _ domainPager = DomainsServiceOp{}
+
+// EachDomain calls fn for every domain on the account, fetching the domains
+// one page at a time rather than listing them all first. It stops at the first
+// error from fn, which is returned as is, or when the context is cancelled.
+func (c *Client) EachDomain(ctx context.Context, fn func(Domain) error) error {
+	each := func(page []Domain) error {
+		for _, d := range page {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if pager, ok := c.Domains.(domainPager); ok {
+		_, _, err := pager.pages(withAllPages(ctx), nil, each)
+		return err
+	}
+
+	// A replaced Domains service can only be listed all at once.
+	domains, _, err := c.Domains.Index(withAllPages(ctx), nil)
+	if err != nil {
+		return err
+	}
+	return each(domains)
 }
 
 // Show gets details of a domain and requires a non-empty domain name
 func (s DomainsServiceOp) Show(ctx context.Context, name string) (*Domain, *Response, error) {
+	name = s.client.resolveDomain(name)
 	if len(name) < 1 {
 		return nil, nil, NewArgError("name", "cannot be an empty string")
 	}
 
-	path := fmt.Sprintf("%s/%s", domainsBasePath, name)
+	path := fmt.Sprintf("%s/%s", s.basePath, name)
 
-	req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
+	root := new(domainRoot)
+	resp, err := s.client.get(ctx, path, nil, root)
 	if err != nil {
-		return nil, nil, err
+		if s.client.showNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
 	}
 
+	return root.Domain, resp, err
+}
+
+// ShowWithRaw gets details of a domain like Show and also returns the
+// response body exactly as the API sent it, e.g. for caching or auditing. The
+// body is only read once.
+func (s DomainsServiceOp) ShowWithRaw(ctx context.Context, name string) (*Domain, json.RawMessage, *Response, error) {
+	name = s.client.resolveDomain(name)
+	if len(name) < 1 {
+		return nil, nil, nil, NewArgError("name", "cannot be an empty string")
+	}
+
+	path := fmt.Sprintf("%s/%s", s.basePath, name)
+
 	root := new(domainRoot)
-	resp, err := s.client.Do(ctx, req, root)
+	target := &withRaw{v: root}
+	resp, err := s.client.get(ctx, path, nil, target)
 	if err != nil {
-		return nil, resp, err
+		if s.client.showNotFound(err) {
+			return nil, nil, resp, nil
+		}
+		return nil, nil, resp, err
 	}
 
-	return root.Domain, resp, err
+	return root.Domain, target.raw, resp, err
+}
+
+// SetArchiving enables or disables the archiving service of a domain and
+// requires a non-empty domain name.
+func (s DomainsServiceOp) SetArchiving(ctx context.Context, name string, enabled bool) (*Response, error) {
+	return s.edit(ctx, name, "archivingServiceEnabled", enabled)
+}
+
+// SetActiveSync enables or disables the ActiveSync mobile service of a domain
+// and requires a non-empty domain name.
+func (s DomainsServiceOp) SetActiveSync(ctx context.Context, name string, enabled bool) (*Response, error) {
+	return s.edit(ctx, name, "activeSyncMobileServiceEnabled", enabled)
+}
+
+// SetBlackBerry enables or disables the BlackBerry mobile service of a domain
+// and requires a non-empty domain name.
+func (s DomainsServiceOp) SetBlackBerry(ctx context.Context, name string, enabled bool) (*Response, error) {
+	return s.edit(ctx, name, "blackBerryMobileServiceEnabled", enabled)
+}
+
+func (s DomainsServiceOp) edit(ctx context.Context, name, field string, enabled bool) (*Response, error) {
+	name = s.client.resolveDomain(name)
+	if len(name) < 1 {
+		return nil, NewArgError("name", "cannot be an empty string")
+	}
+
+	body := map[string]string{field: strconv.FormatBool(enabled)}
+
+	path := fmt.Sprintf("%s/%s", s.basePath, name)
+
+	req, err := s.client.NewRequest(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(ctx, req, nil)
+
+	return resp, err
+}
+
+// DomainAliasCounts returns the number of Rackspace Email aliases of every
+// domain on the account, keyed by domain name. The domains are listed first,
+// then up to concurrency Count calls are made at a time, each waiting on the
+// GET rate limiter. It stops at the first error and returns it with the
+// counts fetched so far. Like the alias batch operations, it fails with
+// ErrServiceUnavailable if SetPreflightStatusCheck is enabled and the API is
+// down.
+func (c *Client) DomainAliasCounts(ctx context.Context, concurrency int) (map[string]int, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if err := c.preflight(ctx); err != nil {
+		return nil, err
+	}
+
+	domains, _, err := c.Domains.Index(withAllPages(ctx), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	counts := make(map[string]int, len(domains))
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	names := make(chan string)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				count, _, err := c.RackspaceEmailAliases.Count(ctx, name)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					counts[name] = count
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, d := range domains {
+		if ctx.Err() != nil {
+			break
+		}
+		names <- d.Name
+	}
+	close(names)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return counts, firstErr
 }
@@ -21,10 +21,14 @@
 package reago
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"testing"
+
+	"golang.org/x/time/rate"
 )
 
 func TestDomains_Index(t *testing.T) {
@@ -74,6 +78,37 @@ func TestDomains_Index_MultiplePages(t *testing.T) {
 	}
 }
 
+func TestDomains_IndexWithTotal(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	responses := []string{
+		`{"offset": 0, "size": 2, "total": 5, "domains": [{"name":"a.com"},{"name":"b.com"}]}`,
+		`{"offset": 2, "size": 2, "total": 5, "domains": [{"name":"c.com"},{"name":"d.com"}]}`,
+		`{"offset": 4, "size": 2, "total": 5, "domains": [{"name":"e.com"}]}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	domains, total, _, err := client.Domains.IndexWithTotal(ctx, &PageOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("Domains.IndexWithTotal returned error: %v", err)
+	}
+
+	if len(domains) != 5 {
+		t.Errorf("Domains.IndexWithTotal returned %d domains, expected %d", len(domains), 5)
+	}
+	if total != 5 {
+		t.Errorf("Domains.IndexWithTotal total = %d, expected %d", total, 5)
+	}
+}
+
 func TestDomains_Show_NoName(t *testing.T) {
 	setup()
 	defer teardown()
@@ -107,3 +142,480 @@ func TestDomains_Show(t *testing.T) {
 		t.Errorf("Domains.Show returned %+v, expected %+v", domains, expected)
 	}
 }
+
+func TestDomains_Show_NotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Domain not found"}`)
+	})
+
+	domain, _, err := client.Domains.Show(ctx, "foo.com")
+	if !isNotFound(err) {
+		t.Errorf("Domains.Show returned %v, expected a not found error", err)
+	}
+	if domain != nil {
+		t.Errorf("Domains.Show returned %+v, expected nil", domain)
+	}
+}
+
+func TestDomains_Show_NotFoundNil(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetShowNotFoundNil(true)(client); err != nil {
+		t.Fatalf("SetShowNotFoundNil(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Domain not found"}`)
+	})
+
+	domain, resp, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Errorf("Domains.Show returned error: %v", err)
+	}
+	if domain != nil {
+		t.Errorf("Domains.Show returned %+v, expected nil", domain)
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Domains.Show should have returned the 404 response")
+	}
+}
+
+func TestDomains_ShowWithRaw(t *testing.T) {
+	setup()
+	defer teardown()
+
+	body := `{"domain": {"name":"foo.com","serviceType":"both","futureField":1}}`
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, body+"\n")
+	})
+
+	domain, raw, _, err := client.Domains.ShowWithRaw(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.ShowWithRaw returned error: %v", err)
+	}
+
+	if string(raw) != body {
+		t.Errorf("Domains.ShowWithRaw raw = %s, expected %s", raw, body)
+	}
+
+	root := new(domainRoot)
+	if err := json.Unmarshal(raw, root); err != nil {
+		t.Fatalf("raw body is not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(domain, root.Domain) {
+		t.Errorf("Domains.ShowWithRaw returned %+v, expected it to match the raw body %+v", domain, root.Domain)
+	}
+}
+
+func TestDomains_Index_ZeroSize(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if size := r.URL.Query().Get("size"); size != "50" {
+			t.Errorf("Request size = %v, expected %v", size, 50)
+		}
+		requests++
+		fmt.Fprint(w, `{"offset": 0, "size": 0, "total": 2, "domains": [{"name":"foo.com"}]}`)
+	})
+
+	domains, _, err := client.Domains.Index(ctx, &PageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Domains.Index made %d requests, expected 1", requests)
+	}
+
+	expected := []Domain{{Name: "foo.com"}}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Domains.Index returned %+v, expected %+v", domains, expected)
+	}
+}
+
+func TestDomains_Index_ShrinkingTotal(t *testing.T) {
+	setup()
+	defer teardown()
+
+	responses := []string{
+		`{"offset": 0, "size": 2, "total": 5, "domains": [{"name":"foo.com"},{"name":"bar.com"}]}`,
+		`{"offset": 2, "size": 2, "total": 3, "domains": [{"name":"baz.com"}]}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		if index >= len(responses) {
+			t.Errorf("Domains.Index requested too many pages")
+			return
+		}
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	domains, _, err := client.Domains.Index(ctx, &PageOptions{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Domain{{Name: "foo.com"}, {Name: "bar.com"}, {Name: "baz.com"}}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Domains.Index returned %+v, expected %+v", domains, expected)
+	}
+}
+
+func TestDomains_Index_PartialResults(t *testing.T) {
+	setup()
+	defer teardown()
+
+	index := 0
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		if index > 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 2, "domains": [{"name":"foo.com"}]}`)
+		index++
+	})
+
+	domains, resp, err := client.Domains.Index(ctx, &PageOptions{Size: 1})
+	if err == nil {
+		t.Fatalf("Domains.Index should have returned an error")
+	}
+	if resp == nil || resp.Response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Domains.Index should have returned the failed response")
+	}
+
+	expected := []Domain{{Name: "foo.com"}}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Domains.Index returned %+v, expected %+v", domains, expected)
+	}
+}
+
+func TestDomains_Index_MaxPageSize(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		if size := r.URL.Query().Get("size"); size != "250" {
+			t.Errorf("Request size = %v, expected %v", size, 250)
+		}
+		fmt.Fprint(w, `{"domains": [{"name":"foo.com"}]}`)
+	})
+
+	if _, _, err := client.Domains.Index(ctx, &PageOptions{Size: maxPageSize}); err != nil {
+		t.Errorf("Domains.Index returned error for the maximum page size: %v", err)
+	}
+
+	_, _, err := client.Domains.Index(ctx, &PageOptions{Size: maxPageSize + 1})
+	if _, ok := err.(*ArgError); !ok {
+		t.Errorf("Domains.Index returned %v, expected an ArgError", err)
+	}
+}
+
+func TestDomains_Show_Extra(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com", "ServiceType":"rsemail", "newFeatureEnabled": true, "quota": {"max": 10}}}`)
+	})
+
+	domain, _, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	if domain.Name != "foo.com" || domain.ServiceType != "rsemail" {
+		t.Errorf("Domains.Show returned %+v, expected the known fields to be decoded", domain)
+	}
+
+	expected := map[string]json.RawMessage{
+		"newFeatureEnabled": json.RawMessage(`true`),
+		"quota":             json.RawMessage(`{"max": 10}`),
+	}
+	if !reflect.DeepEqual(domain.Extra, expected) {
+		t.Errorf("Domains.Show Extra = %s, expected %s", domain.Extra, expected)
+	}
+}
+
+func TestDomains_Toggles(t *testing.T) {
+	tests := []struct {
+		field  string
+		toggle func(*Client) (*Response, error)
+		value  string
+	}{
+		{"archivingServiceEnabled", func(c *Client) (*Response, error) { return c.Domains.SetArchiving(ctx, "foo.com", true) }, "true"},
+		{"activeSyncMobileServiceEnabled", func(c *Client) (*Response, error) { return c.Domains.SetActiveSync(ctx, "foo.com", false) }, "false"},
+		{"blackBerryMobileServiceEnabled", func(c *Client) (*Response, error) { return c.Domains.SetBlackBerry(ctx, "foo.com", true) }, "true"},
+	}
+
+	for _, tt := range tests {
+		setup()
+
+		mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodPut)
+			if err := r.ParseForm(); err != nil {
+				t.Error(err)
+			}
+			if len(r.PostForm) != 1 || r.PostForm.Get(tt.field) != tt.value {
+				t.Errorf("Request form = %v, expected %s=%s", r.PostForm, tt.field, tt.value)
+			}
+		})
+
+		if _, err := tt.toggle(client); err != nil {
+			t.Errorf("%s toggle returned error: %v", tt.field, err)
+		}
+
+		teardown()
+	}
+}
+
+func TestDomains_SetArchiving_NoName(t *testing.T) {
+	_, err := client.Domains.SetArchiving(ctx, "", true)
+	if err == nil {
+		t.Errorf("Domains.SetArchiving should have returned an error for an empty domain")
+	}
+}
+
+func TestEachDomain(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	responses := []string{
+		`{"offset": 0, "size": 2, "total": 3, "domains": [{"name":"a.com"},{"name":"b.com"}]}`,
+		`{"offset": 2, "size": 2, "total": 3, "domains": [{"name":"c.com"}]}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	var names []string
+	err := client.EachDomain(ctx, func(d Domain) error {
+		names = append(names, d.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachDomain returned error: %v", err)
+	}
+
+	expected := []string{"a.com", "b.com", "c.com"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("EachDomain called fn with %v, expected %v", names, expected)
+	}
+}
+
+func TestEachDomain_StopsOnError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"offset": 0, "size": 2, "total": 4, "domains": [{"name":"a.com"},{"name":"b.com"}]}`)
+	})
+
+	stop := errors.New("stop")
+	var names []string
+	err := client.EachDomain(ctx, func(d Domain) error {
+		names = append(names, d.Name)
+		return stop
+	})
+	if err != stop {
+		t.Errorf("EachDomain returned %v, expected %v", err, stop)
+	}
+
+	if expected := []string{"a.com"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("EachDomain called fn with %v, expected %v", names, expected)
+	}
+	if requests != 1 {
+		t.Errorf("Server received %d requests, expected the next page not to be fetched", requests)
+	}
+}
+
+func TestDomains_Index_NextOffset(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	// The server skips ahead to offset 5 instead of the computed 2.
+	responses := map[string]string{
+		"0": `{"offset": 0, "size": 2, "total": 6, "nextOffset": 5, "domains": [{"name":"a.com"},{"name":"b.com"}]}`,
+		"5": `{"offset": 5, "size": 2, "total": 6, "domains": [{"name":"f.com"}]}`,
+	}
+	var offsets []string
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		offset := r.URL.Query().Get("offset")
+		if offset == "" {
+			offset = "0"
+		}
+		offsets = append(offsets, offset)
+		fmt.Fprint(w, responses[offset])
+	})
+
+	domains, _, err := client.Domains.Index(ctx, &PageOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("Domains.Index returned error: %v", err)
+	}
+
+	if expected := []string{"0", "5"}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("Domains.Index requested offsets %v, expected %v", offsets, expected)
+	}
+	expected := []Domain{{Name: "a.com"}, {Name: "b.com"}, {Name: "f.com"}}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Domains.Index returned %+v, expected %+v", domains, expected)
+	}
+}
+
+func TestDomains_Index_NextOffsetBackwards(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"offset": 2, "size": 2, "total": 6, "next": 2, "domains": [{"name":"c.com"}]}`)
+	})
+
+	if _, _, err := client.Domains.Index(ctx, &PageOptions{Offset: 2, Size: 2}); err != nil {
+		t.Fatalf("Domains.Index returned error: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Server received %d requests, expected a next offset that does not advance to stop paging", requests)
+	}
+}
+
+func TestSetPageAdvance(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	// Page numbers instead of offsets
+	advance := func(offset, size, total int) (int, bool) {
+		if offset+1 >= total {
+			return 0, false
+		}
+		return offset + 1, true
+	}
+	if err := SetPageAdvance(advance)(client); err != nil {
+		t.Fatalf("SetPageAdvance(): %v", err)
+	}
+
+	var offsets []string
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		offset := len(offsets) - 1
+		fmt.Fprintf(w, `{"offset": %d, "size": 50, "total": 3, "domains": [{"name":"d%d.com"}]}`, offset, offset)
+	})
+
+	if _, _, err := client.Domains.Index(ctx, nil); err != nil {
+		t.Fatalf("Domains.Index returned error: %v", err)
+	}
+
+	if expected := []string{"", "1", "2"}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("Domains.Index requested offsets %v, expected %v", offsets, expected)
+	}
+}
+
+func TestDomainAliasCounts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"offset": 0, "size": 2, "total": 2, "domains": [{"name":"a.com"},{"name":"b.com"}]}`)
+	})
+	mux.HandleFunc("/v1/domains/a.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 3, "aliases": [{"name":"foo"}]}`)
+	})
+	mux.HandleFunc("/v1/domains/b.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 5, "aliases": [{"name":"bar"}]}`)
+	})
+
+	counts, err := client.DomainAliasCounts(ctx, 2)
+	if err != nil {
+		t.Fatalf("DomainAliasCounts returned error: %v", err)
+	}
+
+	expected := map[string]int{"a.com": 3, "b.com": 5}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("DomainAliasCounts returned %v, expected %v", counts, expected)
+	}
+}
+
+func TestDomainAliasCounts_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 1, "domains": [{"name":"a.com"}]}`)
+	})
+	mux.HandleFunc("/v1/domains/a.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "boom"}`, http.StatusInternalServerError)
+	})
+
+	if _, err := client.DomainAliasCounts(ctx, 1); err == nil {
+		t.Errorf("DomainAliasCounts should have returned an error")
+	}
+}
+
+func TestSetAutoPaginate(t *testing.T) {
+	for _, auto := range []bool{true, false} {
+		t.Run(fmt.Sprintf("auto=%t", auto), func(t *testing.T) {
+			setup()
+			defer teardown()
+
+			client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+			if err := SetAutoPaginate(auto)(client); err != nil {
+				t.Fatalf("SetAutoPaginate(): %v", err)
+			}
+
+			responses := map[string]string{
+				"":  `{"offset": 0, "size": 1, "total": 2, "domains": [{"name":"a.com"}]}`,
+				"1": `{"offset": 1, "size": 1, "total": 2, "domains": [{"name":"b.com"}]}`,
+			}
+			requests := 0
+
+			mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				fmt.Fprint(w, responses[r.URL.Query().Get("offset")])
+			})
+
+			domains, _, err := client.Domains.Index(ctx, &PageOptions{Size: 1})
+			if err != nil {
+				t.Fatalf("Domains.Index returned error: %v", err)
+			}
+
+			expected := []Domain{{Name: "a.com"}, {Name: "b.com"}}
+			if !auto {
+				expected = expected[:1]
+			}
+			if !reflect.DeepEqual(domains, expected) {
+				t.Errorf("Domains.Index returned %+v, expected %+v", domains, expected)
+			}
+			if requests != len(expected) {
+				t.Errorf("Domains.Index sent %d requests, expected %d", requests, len(expected))
+			}
+		})
+	}
+}
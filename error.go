@@ -20,7 +20,22 @@
 
 package reago
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrMissingCredentials is returned when a request is made by a client
+// without a user key or secret key, rather than sending a request the API
+// would reject with a 401.
+var ErrMissingCredentials = errors.New("missing credentials: set the user key and secret key")
+
+// ErrAlreadyExists is returned by create-only operations when the resource
+// already exists.
+var ErrAlreadyExists = errors.New("already exists")
 
 // ArgError is an error that represents an error with an input to reago. It
 // identifies the argument and the cause (if possible).
@@ -43,3 +58,62 @@ func NewArgError(arg, reason string) *ArgError {
 func (e *ArgError) Error() string {
 	return fmt.Sprintf("%s is invalid because %s", e.arg, e.reason)
 }
+
+// ErrServiceUnavailable is matched by errors.Is when the API is unavailable,
+// e.g. during a maintenance window.
+var ErrServiceUnavailable = errors.New("service unavailable")
+
+// ServiceUnavailableError is returned when the API answers with a 503 that
+// is not a JSON API error, as it does during maintenance windows.
+type ServiceUnavailableError struct {
+	// HTTP response that caused this error
+	Response *http.Response
+
+	// RetryAfter is how long the API asked callers to wait before retrying,
+	// or zero if it did not say.
+	RetryAfter time.Duration
+}
+
+var _ error = &ServiceUnavailableError{}
+
+// Error stringifies a ServiceUnavailableError.
+func (e *ServiceUnavailableError) Error() string {
+	msg := fmt.Sprintf("%v %v: %d %v",
+		e.Response.Request.Method, e.Response.Request.URL, e.Response.StatusCode, ErrServiceUnavailable)
+	if e.RetryAfter > 0 {
+		msg = fmt.Sprintf("%s (retry after %v)", msg, e.RetryAfter)
+	}
+	return msg
+}
+
+// Unwrap makes errors.Is(err, ErrServiceUnavailable) true.
+func (e *ServiceUnavailableError) Unwrap() error {
+	return ErrServiceUnavailable
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date relative to now. It returns zero if the header is missing or
+// invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// isNotFound reports whether err is an API error with a 404 status code.
+func isNotFound(err error) bool {
+	var errorResponse *ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.Response == nil {
+		return false
+	}
+	return errorResponse.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,176 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	exchangeMailboxesBasePath = "v1/domains/%s/ex/mailboxes"
+	rackspaceEmailServiceType = "rsemail"
+)
+
+// ExchangeService is an interface for managing Exchange specific mailbox
+// features with the Rackspace Email API. Every method first looks up the
+// domain and refuses to continue if it is a Rackspace Email only domain.
+//
+// See: http://api-wiki.apps.rackspace.com/api-wiki/index.php?title=Exchange_Mailbox_(Rest_API)
+type ExchangeService interface {
+	Show(context.Context, string, string) (*ExchangeMailbox, *Response, error)
+	SetLitigationHold(context.Context, string, string, bool) (*Response, error)
+	SetSharedCalendars(context.Context, string, string, bool) (*Response, error)
+}
+
+// ExchangeServiceOp handles communication with the Exchange mailbox related
+// methods of the Rackspace Email API.
+type ExchangeServiceOp struct {
+	client   *Client
+	basePath string
+}
+
+var _ ExchangeService = &ExchangeServiceOp{}
+
+func (s *ExchangeServiceOp) setBasePath(path string) {
+	s.basePath = path
+}
+
+// ExchangeMailbox represents the Exchange specific features of a mailbox.
+type ExchangeMailbox struct {
+	Name                   string `json:"name"`
+	DisplayName            string `json:"displayName"`
+	LitigationHoldEnabled  bool   `json:"litigationHoldEnabled"`
+	SharedCalendarsEnabled bool   `json:"sharedCalendarsEnabled"`
+	IsResourceMailbox      bool   `json:"isResourceMailbox"`
+
+	// Extra holds the fields returned by the API that are not modeled above
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a mailbox, keeping unmodeled fields in Extra.
+func (m *ExchangeMailbox) UnmarshalJSON(data []byte) error {
+	type mailbox ExchangeMailbox
+	if err := json.Unmarshal(data, (*mailbox)(m)); err != nil {
+		return err
+	}
+
+	extra, err := extraFields(data, m)
+	if err != nil {
+		return err
+	}
+	m.Extra = extra
+
+	return nil
+}
+
+// checkDomain returns an ArgError if the domain is known to be a Rackspace
+// Email only domain.
+func (s *ExchangeServiceOp) checkDomain(ctx context.Context, domain string) error {
+	d, _, err := s.client.Domains.Show(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	if d != nil && d.ServiceType == rackspaceEmailServiceType {
+		return NewArgError("domain", "it is not an Exchange domain")
+	}
+
+	return nil
+}
+
+// Show gets the Exchange features of a mailbox and requires a non-empty
+// domain name and a non-empty mailbox name.
+func (s *ExchangeServiceOp) Show(ctx context.Context, domain, mailbox string) (*ExchangeMailbox, *Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(mailbox) < 1 {
+		return nil, nil, NewArgError("mailbox", "cannot be an empty string")
+	}
+
+	if err := s.checkDomain(ctx, domain); err != nil {
+		return nil, nil, err
+	}
+
+	path := fmt.Sprintf(s.basePath, domain)
+	path = fmt.Sprintf("%s/%s", path, mailbox)
+
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(ExchangeMailbox)
+	resp, err := s.client.Do(ctx, req, root)
+	if err != nil {
+		if s.client.showNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+
+	return root, resp, err
+}
+
+// SetLitigationHold enables or disables litigation hold on a mailbox and
+// requires a non-empty domain name and a non-empty mailbox name.
+func (s *ExchangeServiceOp) SetLitigationHold(ctx context.Context, domain, mailbox string, enabled bool) (*Response, error) {
+	return s.edit(ctx, domain, mailbox, "litigationHoldEnabled", enabled)
+}
+
+// SetSharedCalendars enables or disables calendar sharing on a mailbox and
+// requires a non-empty domain name and a non-empty mailbox name.
+func (s *ExchangeServiceOp) SetSharedCalendars(ctx context.Context, domain, mailbox string, enabled bool) (*Response, error) {
+	return s.edit(ctx, domain, mailbox, "sharedCalendarsEnabled", enabled)
+}
+
+func (s *ExchangeServiceOp) edit(ctx context.Context, domain, mailbox, field string, enabled bool) (*Response, error) {
+	domain = s.client.resolveDomain(domain)
+	if len(domain) < 1 {
+		return nil, NewArgError("domain", "cannot be an empty string")
+	}
+	if len(mailbox) < 1 {
+		return nil, NewArgError("mailbox", "cannot be an empty string")
+	}
+
+	if err := s.checkDomain(ctx, domain); err != nil {
+		return nil, err
+	}
+
+	body := map[string]string{field: strconv.FormatBool(enabled)}
+
+	path := fmt.Sprintf(s.basePath, domain)
+	path = fmt.Sprintf("%s/%s", path, mailbox)
+
+	req, err := s.client.NewRequest(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(ctx, req, nil)
+
+	return resp, err
+}
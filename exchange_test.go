@@ -0,0 +1,149 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func handleExchangeDomain(serviceType string) {
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"domain": {"name":"foo.com", "serviceType":"%s"}}`, serviceType)
+	})
+}
+
+func TestExchange_Show_NoDomain(t *testing.T) {
+	_, _, err := client.Exchange.Show(ctx, "", "bar")
+	if err == nil {
+		t.Errorf("Exchange.Show should have returned an error for an empty domain")
+	}
+}
+
+func TestExchange_Show_NoMailbox(t *testing.T) {
+	_, _, err := client.Exchange.Show(ctx, "foo.com", "")
+	if err == nil {
+		t.Errorf("Exchange.Show should have returned an error for an empty mailbox")
+	}
+}
+
+func TestExchange_Show(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleExchangeDomain("exchange")
+	mux.HandleFunc("/v1/domains/foo.com/ex/mailboxes/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name": "bar", "litigationHoldEnabled": true, "isResourceMailbox": true}`)
+	})
+
+	mailbox, _, err := client.Exchange.Show(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("Exchange.Show returned error: %v", err)
+	}
+
+	expected := &ExchangeMailbox{
+		Name:                  "bar",
+		LitigationHoldEnabled: true,
+		IsResourceMailbox:     true,
+	}
+	if !reflect.DeepEqual(mailbox, expected) {
+		t.Errorf("Exchange.Show returned %+v, expected %+v", mailbox, expected)
+	}
+}
+
+func TestExchange_Show_Extra(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleExchangeDomain("exchange")
+	mux.HandleFunc("/v1/domains/foo.com/ex/mailboxes/bar", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "bar", "litigationHoldEnabled": true, "archiveEnabled": true}`)
+	})
+
+	mailbox, _, err := client.Exchange.Show(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("Exchange.Show returned error: %v", err)
+	}
+
+	if mailbox.Name != "bar" || !mailbox.LitigationHoldEnabled {
+		t.Errorf("Exchange.Show returned %+v, expected the known fields to be decoded", mailbox)
+	}
+
+	expected := map[string]json.RawMessage{"archiveEnabled": json.RawMessage(`true`)}
+	if !reflect.DeepEqual(mailbox.Extra, expected) {
+		t.Errorf("Exchange.Show Extra = %s, expected %s", mailbox.Extra, expected)
+	}
+}
+
+func TestExchange_Show_RackspaceEmailDomain(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleExchangeDomain("rsemail")
+	mux.HandleFunc("/v1/domains/foo.com/ex/mailboxes/bar", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Exchange.Show should not have called the mailbox endpoint")
+	})
+
+	_, _, err := client.Exchange.Show(ctx, "foo.com", "bar")
+	if _, ok := err.(*ArgError); !ok {
+		t.Errorf("Exchange.Show returned %v, expected an ArgError", err)
+	}
+}
+
+func TestExchange_SetLitigationHold(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleExchangeDomain("exchange")
+	mux.HandleFunc("/v1/domains/foo.com/ex/mailboxes/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+		if v := r.FormValue("litigationHoldEnabled"); v != "true" {
+			t.Errorf("Request litigationHoldEnabled = %v, expected %v", v, "true")
+		}
+	})
+
+	_, err := client.Exchange.SetLitigationHold(ctx, "foo.com", "bar", true)
+	if err != nil {
+		t.Errorf("Exchange.SetLitigationHold returned error: %v", err)
+	}
+}
+
+func TestExchange_SetSharedCalendars(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleExchangeDomain("exchange")
+	mux.HandleFunc("/v1/domains/foo.com/ex/mailboxes/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPut)
+		if v := r.FormValue("sharedCalendarsEnabled"); v != "false" {
+			t.Errorf("Request sharedCalendarsEnabled = %v, expected %v", v, "false")
+		}
+	})
+
+	_, err := client.Exchange.SetSharedCalendars(ctx, "foo.com", "bar", false)
+	if err != nil {
+		t.Errorf("Exchange.SetSharedCalendars returned error: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// JobStateCompleted is the state of a job that finished successfully.
+	JobStateCompleted = "completed"
+
+	// JobStateFailed is the state of a job that finished unsuccessfully.
+	JobStateFailed = "failed"
+)
+
+// JobStatus represents the status of an asynchronous job returned by
+// endpoints that answer with 202 Accepted.
+type JobStatus struct {
+	ID      string          `json:"id"`
+	State   string          `json:"state"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// WaitForJob polls the job resource at jobURL every interval until the job
+// reports that it has completed or failed. A failed job is returned along
+// with an error. jobURL may be relative to the BaseURL of the Client, or
+// absolute with the same scheme and host as the BaseURL, since every poll is
+// signed with the client's credentials.
+func (c *Client) WaitForJob(ctx context.Context, jobURL string, interval time.Duration) (*JobStatus, error) {
+	if len(jobURL) < 1 {
+		return nil, NewArgError("jobURL", "cannot be an empty string")
+	}
+	if interval <= 0 {
+		return nil, NewArgError("interval", "it must be greater than zero")
+	}
+
+	u, err := url.Parse(jobURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "" || u.Host != "") && (u.Scheme != c.BaseURL.Scheme || u.Host != c.BaseURL.Host) {
+		return nil, NewArgError("jobURL", fmt.Sprintf("it must be on the API host %s", c.BaseURL.Host))
+	}
+
+	// Every poll must reach the API, the status is expected to change.
+	pollCtx := withJobPoll(WithCacheBypass(ctx))
+	for {
+		req, err := c.NewRequest(pollCtx, http.MethodGet, jobURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		status := new(JobStatus)
+		_, err = c.Do(pollCtx, req, status)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status.State {
+		case JobStateCompleted:
+			return status, nil
+		case JobStateFailed:
+			return status, fmt.Errorf("job %s failed: %s", status.ID, status.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-c.clock.After(interval):
+		}
+	}
+}
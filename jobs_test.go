@@ -0,0 +1,151 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForJob(t *testing.T) {
+	setup()
+	defer teardown()
+
+	responses := []string{
+		`{"id": "1", "state": "inProgress"}`,
+		`{"id": "1", "state": "completed", "result": {"name": "foo"}}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	status, err := client.WaitForJob(ctx, "v1/jobs/1", time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForJob returned error: %v", err)
+	}
+
+	if status.State != JobStateCompleted {
+		t.Errorf("WaitForJob State = %v, expected %v", status.State, JobStateCompleted)
+	}
+	if string(status.Result) != `{"name": "foo"}` {
+		t.Errorf("WaitForJob Result = %s, expected %s", status.Result, `{"name": "foo"}`)
+	}
+	if index != 2 {
+		t.Errorf("WaitForJob polled %d times, expected 2", index)
+	}
+}
+
+func TestWaitForJob_Failed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "1", "state": "failed", "message": "boom"}`)
+	})
+
+	status, err := client.WaitForJob(ctx, "v1/jobs/1", time.Millisecond)
+	if err == nil {
+		t.Fatalf("WaitForJob should have returned an error for a failed job")
+	}
+	if status == nil || status.State != JobStateFailed {
+		t.Errorf("WaitForJob returned %+v, expected a failed status", status)
+	}
+}
+
+func TestWaitForJob_InvalidInterval(t *testing.T) {
+	_, err := client.WaitForJob(ctx, "v1/jobs/1", 0)
+	if err == nil {
+		t.Errorf("WaitForJob should have returned an error for a zero interval")
+	}
+}
+
+func TestWaitForJob_Cache(t *testing.T) {
+	setup()
+	defer teardown()
+
+	setupCache(t, time.Hour)
+
+	responses := []string{
+		`{"id": "1", "state": "inProgress"}`,
+		`{"id": "1", "state": "completed"}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+		if index >= len(responses) {
+			t.Errorf("WaitForJob polled more than %d times", len(responses))
+			return
+		}
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	status, err := client.WaitForJob(ctx, "v1/jobs/1", time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForJob returned error: %v", err)
+	}
+
+	if status.State != JobStateCompleted {
+		t.Errorf("WaitForJob State = %v, expected %v", status.State, JobStateCompleted)
+	}
+	if index != 2 {
+		t.Errorf("WaitForJob polled %d times, expected 2", index)
+	}
+}
+
+func TestWaitForJob_AbsoluteURL(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "1", "state": "completed"}`)
+	})
+
+	if _, err := client.WaitForJob(ctx, server.URL+"/v1/jobs/1", time.Millisecond); err != nil {
+		t.Errorf("WaitForJob returned error: %v", err)
+	}
+}
+
+func TestWaitForJob_ForeignHost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No request should have been sent, got %s %s", r.Method, r.URL)
+	})
+
+	for _, jobURL := range []string{
+		"https://attacker.example.com/v1/jobs/1",
+		"//attacker.example.com/v1/jobs/1",
+		strings.Replace(server.URL, "http://", "https://", 1) + "/v1/jobs/1",
+	} {
+		if _, err := client.WaitForJob(ctx, jobURL, time.Millisecond); err == nil {
+			t.Errorf("WaitForJob should have returned an error for %s", jobURL)
+		}
+	}
+}
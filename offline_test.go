@@ -0,0 +1,145 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetOffline(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No request should have been sent, got %s %s", r.Method, r.URL)
+	})
+
+	c, err := New(nil, SetBaseURL(server.URL), SetOffline(true))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	var log bytes.Buffer
+	c.offlineLog = &log
+
+	domains, _, err := c.Domains.Index(ctx, nil)
+	if err != nil {
+		t.Fatalf("Domains.Index returned error: %v", err)
+	}
+	if len(domains) != 0 {
+		t.Errorf("Domains.Index returned %+v, expected no domains", domains)
+	}
+
+	alias, _, err := c.RackspaceEmailAliases.Show(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Show returned error: %v", err)
+	}
+	if alias == nil || alias.Name != "bar" || !reflect.DeepEqual(alias.EmailAddressList.Addresses, []string{offlineMember}) {
+		t.Errorf("RackspaceEmailAliases.Show returned %+v, expected alias bar with member %s", alias, offlineMember)
+	}
+
+	if _, err := c.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{"a@foo.com"}); err != nil {
+		t.Errorf("RackspaceEmailAliases.Add returned error: %v", err)
+	}
+	if _, err := c.RackspaceEmailAliases.Delete(ctx, "foo.com", "bar"); err != nil {
+		t.Errorf("RackspaceEmailAliases.Delete returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	expected := []string{
+		"Offline: GET " + server.URL + "/v1/domains?size=50",
+		"Offline: GET " + server.URL + "/v1/domains/foo.com/rs/aliases/bar",
+		"Offline: POST " + server.URL + "/v1/domains/foo.com/rs/aliases/bar aliasEmails=a%40foo.com",
+		"Offline: DELETE " + server.URL + "/v1/domains/foo.com/rs/aliases/bar",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Offline log =\n%s\nexpected\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestSetOffline_Helpers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No request should have been sent, got %s %s", r.Method, r.URL)
+	})
+
+	c, err := New(nil, SetBaseURL(server.URL), SetOffline(true))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	c.offlineLog = ioutil.Discard
+
+	domain, _, err := c.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+	if domain == nil || domain.Name != "foo.com" {
+		t.Errorf("Domains.Show returned %+v, expected a zero value domain named foo.com", domain)
+	}
+
+	exists, _, err := c.RackspaceEmailAliases.Exists(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Exists returned error: %v", err)
+	}
+	if exists {
+		t.Errorf("RackspaceEmailAliases.Exists returned true, expected false")
+	}
+
+	if _, err := c.RackspaceEmailAliases.AddIfNotExists(ctx, "foo.com", "bar", []string{"a@foo.com"}); err != nil {
+		t.Errorf("RackspaceEmailAliases.AddIfNotExists returned error: %v", err)
+	}
+	if _, err := c.RackspaceEmailAliases.Rename(ctx, "foo.com", "bar", "baz"); err != nil {
+		t.Errorf("RackspaceEmailAliases.Rename returned error: %v", err)
+	}
+	if _, _, err := c.RackspaceEmailAliases.Disable(ctx, "foo.com", "bar"); err != nil {
+		t.Errorf("RackspaceEmailAliases.Disable returned error: %v", err)
+	}
+	if _, err := c.RackspaceEmailAliases.Enable(ctx, "foo.com", "bar", nil); err != nil {
+		t.Errorf("RackspaceEmailAliases.Enable returned error: %v", err)
+	}
+}
+
+func TestSetOffline_WaitForJob(t *testing.T) {
+	c, err := New(nil, SetOffline(true))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	c.offlineLog = ioutil.Discard
+
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	status, err := c.WaitForJob(tctx, "v1/jobs/1", time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForJob returned error: %v", err)
+	}
+	if status.State != JobStateCompleted {
+		t.Errorf("WaitForJob State = %v, expected %v", status.State, JobStateCompleted)
+	}
+}
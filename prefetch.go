@@ -0,0 +1,101 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+	"sync"
+)
+
+// remainingOffsets returns the offsets of the pages after p when they can be
+// worked out from p alone, i.e. the response carries a total, no explicit
+// next offset and the client uses DefaultPageAdvance.
+func (c *Client) remainingOffsets(p page) ([]int, bool) {
+	if c.pageAdvance != nil || p.Next != nil || p.NextOffset != nil || p.Total < 1 || p.Size < 1 {
+		return nil, false
+	}
+
+	var offsets []int
+	for offset, more := DefaultPageAdvance(p.Offset, p.Size, p.Total); more; offset, more = DefaultPageAdvance(offset, p.Size, p.Total) {
+		offsets = append(offsets, offset)
+	}
+	return offsets, true
+}
+
+// prefetch calls fetch with the index and value of every offset, up to
+// c.pagePrefetch calls at a time. It stops at the first error, which is
+// returned, or when the context is cancelled.
+func (c *Client) prefetch(ctx context.Context, offsets []int, fetch func(ctx context.Context, i, offset int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+
+	for w := 0; w < c.pagePrefetch; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fetch(ctx, i, offsets[i]); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range offsets {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// SetPagePrefetch is a client option for fetching the pages of a listing
+// concurrently, up to concurrency at a time, each waiting on the GET rate
+// limiter. It applies once the first page gives a usable total and the
+// default page advance is in use, otherwise pages are fetched one after the
+// other. The pages are still returned in order. A concurrency of zero or one
+// fetches pages sequentially, which is the default.
+func SetPagePrefetch(concurrency int) func(*Client) error {
+	return func(c *Client) error {
+		if concurrency < 0 {
+			return NewArgError("concurrency", "it cannot be negative")
+		}
+		c.pagePrefetch = concurrency
+		return nil
+	}
+}
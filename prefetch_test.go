@@ -0,0 +1,156 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSetPagePrefetch_Domains(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+	if err := SetPagePrefetch(3)(client); err != nil {
+		t.Fatalf("SetPagePrefetch(): %v", err)
+	}
+
+	var mu sync.Mutex
+	var offsets []int
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		mu.Lock()
+		offsets = append(offsets, offset)
+		mu.Unlock()
+
+		fmt.Fprintf(w, `{"offset": %d, "size": 2, "total": 8, "domains": [{"name":"%d.com"},{"name":"%d.com"}]}`, offset, offset, offset+1)
+	})
+
+	domains, _, err := client.Domains.Index(ctx, &PageOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("Domains.Index returned error: %v", err)
+	}
+
+	var names []string
+	for _, d := range domains {
+		names = append(names, d.Name)
+	}
+	expected := []string{"0.com", "1.com", "2.com", "3.com", "4.com", "5.com", "6.com", "7.com"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Domains.Index returned %v, expected %v", names, expected)
+	}
+	if len(offsets) != 4 {
+		t.Errorf("Domains.Index requested offsets %v, expected 4 pages", offsets)
+	}
+}
+
+func TestSetPagePrefetch_Aliases(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+	if err := SetPagePrefetch(2)(client); err != nil {
+		t.Fatalf("SetPagePrefetch(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/domain.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		fmt.Fprintf(w, `{"offset": %d, "size": 1, "total": 4, "aliases": [{"name":"a%d"}]}`, offset, offset)
+	})
+
+	aliases, _, err := client.RackspaceEmailAliases.Index(ctx, &PageOptions{Size: 1}, "domain.com")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Index returned error: %v", err)
+	}
+
+	expected := []RackspaceEmailAlias{{Name: "a0"}, {Name: "a1"}, {Name: "a2"}, {Name: "a3"}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("RackspaceEmailAliases.Index returned %+v, expected %+v", aliases, expected)
+	}
+}
+
+func TestSetPagePrefetch_NoTotal(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+	if err := SetPagePrefetch(2)(client); err != nil {
+		t.Fatalf("SetPagePrefetch(): %v", err)
+	}
+
+	responses := []string{
+		`{"offset": 0, "size": 1, "nextOffset": 1, "domains": [{"name":"a.com"}]}`,
+		`{"offset": 1, "size": 1, "domains": [{"name":"b.com"}]}`,
+	}
+	index := 0
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[index])
+		index++
+	})
+
+	domains, _, err := client.Domains.Index(ctx, &PageOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("Domains.Index returned error: %v", err)
+	}
+
+	expected := []Domain{{Name: "a.com"}, {Name: "b.com"}}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Domains.Index returned %+v, expected %+v", domains, expected)
+	}
+}
+
+func TestSetPagePrefetch_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+	if err := SetPagePrefetch(2)(client); err != nil {
+		t.Fatalf("SetPagePrefetch(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "2" {
+			http.Error(w, `{"message": "boom"}`, http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 3, "domains": [{"name":"a.com"}]}`)
+	})
+
+	if _, _, err := client.Domains.Index(ctx, &PageOptions{Size: 1}); err == nil {
+		t.Errorf("Domains.Index should have returned an error")
+	}
+}
+
+func TestSetPagePrefetch_Invalid(t *testing.T) {
+	if _, err := New(nil, SetPagePrefetch(-1)); err == nil {
+		t.Errorf("SetPagePrefetch should have returned an error for a negative concurrency")
+	}
+}
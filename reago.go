@@ -21,19 +21,22 @@
 package reago
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -48,10 +51,15 @@ const (
 	userAgent                 = "reago/" + libraryVersion
 	mediaType                 = "application/json"
 	defaultPageSize           = 50
+	maxPageSize               = 250
 	defaultGetLimit           = 1.9
 	defaultGetBurst           = 1
 	defaultPutPostDeleteLimit = 1.4
 	defaultPutPostDeleteBurst = 1
+	requestIDHeader           = "X-Request-Id"
+	idempotencyKeyHeader      = "Idempotency-Key"
+	disabledAliasPlaceholder  = "disabled-alias@example.invalid"
+	maxBodySnippet            = 256
 )
 
 // Client manages communication with Rackspace Email v1 API
@@ -59,6 +67,10 @@ type Client struct {
 	// HTTP client used to communicate with the Rackspace Email API.
 	client *http.Client
 
+	// Transport of the HTTP client when it was created by NewClient. It is
+	// nil when a custom HTTP client was supplied.
+	transport *http.Transport
+
 	// Base URL for API requests.
 	BaseURL *url.URL
 
@@ -69,32 +81,154 @@ type Client struct {
 	userKey   string
 	secretKey string
 
+	// Originating client IP sent in the X-Forwarded-For header
+	forwardedFor string
+
+	// Language sent in the Accept-Language header
+	acceptLanguage string
+
+	// Domain used by services when called with an empty domain
+	defaultDomain string
+
+	// Customer whose domains are addressed unless the context carries one
+	customerID string
+
+	// Sole member of the aliases disabled by RackspaceEmailAliases.Disable
+	disabledAliasPlaceholder string
+
+	Accounts              AccountsService
 	RackspaceEmailAliases RackspaceEmailAliasesService
 	Domains               DomainsService
+	Exchange              ExchangeService
 
 	debugHTTP bool
 
+	clock Clock
+
 	getLimiter           *rate.Limiter
 	putPostDeleteLimiter *rate.Limiter
+
+	// Shares identical in-flight GETs when not nil
+	getGroup *flightGroup
+
+	// Shares identical in-flight POSTs, PUTs and DELETEs when not nil
+	mutationGroup *flightGroup
+
+	// Called after every HTTP request when not nil
+	metricsHook func(method string, status int, dur time.Duration)
+
+	// Adjusts the rate limiters to 429 responses when not nil
+	adaptive *adaptiveLimits
+
+	// Makes Show methods return a nil result instead of a 404 error
+	showNotFoundNil bool
+
+	// Skips signing, and so the credentials check, when replaying fixtures
+	unsigned bool
+
+	// Serves recent successful GET responses when not nil
+	cache *responseCache
+
+	// Formats API errors returned by the client when not nil
+	errorFormatter func(*ErrorResponse) string
+
+	// Probes the API before batch operations
+	preflightStatusCheck bool
+
+	// Rate limit each domain separately when not nil
+	domainLimiters *domainLimiters
+
+	// Answers requests without sending them, logging them to offlineLog
+	offline    bool
+	offlineLog io.Writer
+
+	// Decides the offset of the next page when not nil
+	pageAdvance PageAdvanceFunc
+
+	// Fetches up to this many pages of a listing at once when above one
+	pagePrefetch int
+
+	// Lists only the requested page instead of following the next pages
+	singlePage bool
 }
 
-// PageOptions specifies the request pagination options
+// PageOptions specifies the request pagination options. The Rackspace Email
+// API only supports offset based pagination, so a listing is not a consistent
+// snapshot: records added or removed while the pages are being fetched can be
+// skipped or returned twice.
 type PageOptions struct {
 	Offset int `url:"offset,omitempty"`
 	Size   int `url:"size,omitempty"`
 }
 
-// NewClient returns a Rackspace Email API client
+// PageAdvanceFunc returns the offset of the page that follows the page at
+// offset, given the size and total reported for it, and false if it was the
+// last page.
+type PageAdvanceFunc func(offset, size, total int) (next int, more bool)
+
+// DefaultPageAdvance is the PageAdvanceFunc used unless SetPageAdvance is
+// given another one. The next page starts right after the current one, and a
+// page size of zero is treated as the last page since the offset would never
+// advance.
+func DefaultPageAdvance(offset, size, total int) (int, bool) {
+	if size < 1 || total <= offset+size {
+		return 0, false
+	}
+	return offset + size, true
+}
+
+// page holds the pagination fields of a listing response.
+type page struct {
+	Offset int `json:"offset"`
+	Size   int `json:"size"`
+	Total  int `json:"total"`
+
+	// Offset of the next page, if the API says so explicitly
+	Next       *int `json:"next"`
+	NextOffset *int `json:"nextOffset"`
+}
+
+// advance returns the offset of the page after p and false if p was the last
+// page. An explicit next offset in the response is preferred over the
+// client's PageAdvanceFunc, but only if it moves forward.
+func (c *Client) advance(p page) (int, bool) {
+	for _, next := range []*int{p.NextOffset, p.Next} {
+		if next != nil {
+			return *next, *next > p.Offset
+		}
+	}
+
+	advance := c.pageAdvance
+	if advance == nil {
+		advance = DefaultPageAdvance
+	}
+	return advance(p.Offset, p.Size, p.Total)
+}
+
+// NewClient returns a Rackspace Email API client. If httpClient is nil, a
+// client with its own transport is created, which the transport related
+// client options can then configure. When http.DefaultTransport has been
+// replaced by something other than an *http.Transport, the client uses it as
+// is and the transport related options have no effect.
 func NewClient(httpClient *http.Client) *Client {
+	var transport *http.Transport
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		if dt, ok := http.DefaultTransport.(*http.Transport); ok {
+			transport = dt.Clone()
+			httpClient = &http.Client{Transport: transport}
+		} else {
+			httpClient = &http.Client{}
+		}
 	}
 
 	baseURL, _ := url.Parse(defaultBaseURL)
 
-	c := &Client{client: httpClient, BaseURL: baseURL, UserAgent: userAgent}
-	c.RackspaceEmailAliases = &RackspaceEmailAliasesServiceOp{client: c}
-	c.Domains = &DomainsServiceOp{client: c}
+	c := &Client{client: httpClient, transport: transport, BaseURL: baseURL, UserAgent: userAgent, clock: realClock{}}
+	c.disabledAliasPlaceholder = disabledAliasPlaceholder
+	c.Accounts = &AccountsServiceOp{client: c}
+	c.RackspaceEmailAliases = &RackspaceEmailAliasesServiceOp{client: c, basePath: rackspaceEmailAliasesBasePath, disabled: newAliasStash()}
+	c.Domains = &DomainsServiceOp{client: c, basePath: domainsBasePath}
+	c.Exchange = &ExchangeServiceOp{client: c, basePath: exchangeMailboxesBasePath}
 
 	c.getLimiter = rate.NewLimiter(rate.Limit(defaultGetLimit), defaultGetBurst)
 	c.putPostDeleteLimiter = rate.NewLimiter(rate.Limit(defaultPutPostDeleteLimit), defaultPutPostDeleteBurst)
@@ -106,6 +240,40 @@ func NewClient(httpClient *http.Client) *Client {
 // http.Response returned from Rackspace.
 type Response struct {
 	*http.Response
+
+	// RequestID returned from the API, useful to contact support. It is
+	// read from the X-Request-Id header or, failing that, from a request_id
+	// field in a JSON response body.
+	RequestID string
+
+	// Warnings holds the non-fatal warnings of a successful response, read
+	// from a warnings field in a JSON response body, e.g. when some of the
+	// addresses of a created alias were skipped.
+	Warnings []string
+}
+
+// Is2xx reports whether the response has a 2xx status code.
+func (r *Response) Is2xx() bool {
+	code := r.Code()
+	return code >= 200 && code <= 299
+}
+
+// Code returns the status code of the response, or 0 if there is no HTTP
+// response.
+func (r *Response) Code() int {
+	if r == nil || r.Response == nil {
+		return 0
+	}
+	return r.Response.StatusCode
+}
+
+// HeaderValue returns the first value of the response header name, or an
+// empty string if it is not set or there is no HTTP response.
+func (r *Response) HeaderValue(name string) string {
+	if r == nil || r.Response == nil {
+		return ""
+	}
+	return r.Response.Header.Get(name)
 }
 
 // ErrorResponse returns the information from an API error
@@ -118,6 +286,107 @@ type ErrorResponse struct {
 
 	// RequestID returned from the API, useful to contact support.
 	RequestID string `json:"request_id"`
+
+	// Errors holds the per-item errors returned by batch endpoints
+	Errors []ItemError `json:"errors"`
+
+	// Formats Error() when not nil, set from the client's SetErrorFormatter
+	formatter func(*ErrorResponse) string
+}
+
+// ItemError is the error for a single item of a batch API request
+type ItemError struct {
+	Item    string `json:"item"`
+	Message string `json:"message"`
+}
+
+// BatchResult is the outcome for a single item of a batch operation
+type BatchResult struct {
+	// Line is the line of the input the item was read from, if any
+	Line int
+
+	// Name identifies the item, e.g. the alias name
+	Name string
+
+	// Err is nil if the item succeeded
+	Err error
+}
+
+// Timestamp is a point in time returned by the API. It decodes RFC 3339
+// strings, strings in the "2006-01-02T15:04:05" and "2006-01-02 15:04:05"
+// forms, which are taken to be in UTC, and "/Date(milliseconds)/" strings.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts are the string forms accepted by Timestamp.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// UnmarshalJSON decodes a Timestamp from a JSON string. An empty string is
+// decoded as the zero time.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	if strings.HasPrefix(s, "/Date(") && strings.HasSuffix(s, ")/") {
+		ms, err := strconv.ParseInt(s[len("/Date("):len(s)-len(")/")], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse timestamp %q: %w", s, err)
+		}
+		t.Time = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+		return nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot parse timestamp %q", s)
+}
+
+// extraFields returns the top level keys of the JSON object in data that do
+// not map to a field of the struct pointed to by v, or nil if there are none.
+// Like encoding/json, keys are matched case-insensitively.
+func extraFields(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		for k := range raw {
+			if strings.EqualFold(k, name) {
+				delete(raw, k)
+			}
+		}
+	}
+
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return raw, nil
 }
 
 func addOptions(s string, opt interface{}) (string, error) {
@@ -159,7 +428,23 @@ func New(httpClient *http.Client, options ...func(*Client) error) (*Client, erro
 	return c, nil
 }
 
-// SetBaseURL is a client option for setting the base URL.
+// NewWithCredentials returns a new API client instance using the default HTTP
+// client and the given user and secret keys. The remaining options are applied
+// after the keys.
+func NewWithCredentials(userKey, secretKey string, options ...func(*Client) error) (*Client, error) {
+	if len(userKey) < 1 {
+		return nil, NewArgError("userKey", "cannot be an empty string")
+	}
+	if len(secretKey) < 1 {
+		return nil, NewArgError("secretKey", "cannot be an empty string")
+	}
+
+	options = append([]func(*Client) error{SetUserKey(userKey), SetSecretKey(secretKey)}, options...)
+	return New(nil, options...)
+}
+
+// SetBaseURL is a client option for setting the base URL. The URL must be
+// absolute with an http or https scheme and a host.
 func SetBaseURL(bu string) func(*Client) error {
 	return func(c *Client) error {
 		u, err := url.Parse(bu)
@@ -167,19 +452,47 @@ func SetBaseURL(bu string) func(*Client) error {
 			return err
 		}
 
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return NewArgError("baseURL", "it must have an http or https scheme")
+		}
+		if u.Host == "" {
+			return NewArgError("baseURL", "it must have a host")
+		}
+
 		c.BaseURL = u
 		return nil
 	}
 }
 
-// SetUserAgent is a client option for setting the user agent.
+// SetUserAgent is a client option for setting the user agent. The user agent
+// is part of the request signature, so characters that could be altered in
+// transit are rejected.
 func SetUserAgent(ua string) func(*Client) error {
 	return func(c *Client) error {
+		if err := validateUserAgent(ua); err != nil {
+			return err
+		}
 		c.UserAgent = fmt.Sprintf("%s", ua)
 		return nil
 	}
 }
 
+// validateUserAgent returns an ArgError if ua could be altered on its way to
+// the API, which would make the signature computed from it fail. Following RFC
+// 7231 it may only contain visible ASCII characters, spaces and tabs, and it
+// cannot start or end with whitespace, which servers strip.
+func validateUserAgent(ua string) error {
+	for i := 0; i < len(ua); i++ {
+		if b := ua[i]; (b < 0x21 || b > 0x7e) && b != ' ' && b != '\t' {
+			return NewArgError("userAgent", fmt.Sprintf("it contains the illegal character %q", b))
+		}
+	}
+	if strings.TrimSpace(ua) != ua {
+		return NewArgError("userAgent", "it cannot start or end with whitespace")
+	}
+	return nil
+}
+
 // SetUserKey is a client option for setting the user key.
 func SetUserKey(uk string) func(*Client) error {
 	return func(c *Client) error {
@@ -196,6 +509,162 @@ func SetSecretKey(sk string) func(*Client) error {
 	}
 }
 
+// SetUserKeyFromFile is a client option for setting the user key from the
+// contents of a file, e.g. a mounted Kubernetes secret. Surrounding
+// whitespace, such as a trailing newline, is trimmed.
+func SetUserKeyFromFile(path string) func(*Client) error {
+	return func(c *Client) error {
+		uk, err := readKeyFile(path)
+		if err != nil {
+			return err
+		}
+		c.userKey = uk
+		return nil
+	}
+}
+
+// SetSecretKeyFromFile is a client option for setting the secret key from
+// the contents of a file, e.g. a mounted Kubernetes secret. Surrounding
+// whitespace, such as a trailing newline, is trimmed.
+func SetSecretKeyFromFile(path string) func(*Client) error {
+	return func(c *Client) error {
+		sk, err := readKeyFile(path)
+		if err != nil {
+			return err
+		}
+		c.secretKey = sk
+		return nil
+	}
+}
+
+// readKeyFile returns the trimmed contents of the key file at path, failing
+// if the file cannot be read or holds nothing but whitespace.
+func readKeyFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", NewArgError("path", fmt.Sprintf("key file %s is empty", path))
+	}
+	return key, nil
+}
+
+// SetDefaultDomain is a client option for setting the domain used by the
+// services when they are called with an empty domain. A non-empty domain
+// argument always takes precedence over the default, and services still
+// return an error if neither is set.
+func SetDefaultDomain(domain string) func(*Client) error {
+	return func(c *Client) error {
+		c.defaultDomain = domain
+		return nil
+	}
+}
+
+// SetForwardedForHeader is a client option for sending the originating client
+// IP in the X-Forwarded-For header of every request. The header is not part of
+// the request signature.
+func SetForwardedForHeader(ip string) func(*Client) error {
+	return func(c *Client) error {
+		if net.ParseIP(ip) == nil {
+			return NewArgError("ip", "it is not a valid IP address")
+		}
+
+		c.forwardedFor = ip
+		return nil
+	}
+}
+
+// basePathSetter is implemented by services whose base path can be
+// overridden.
+type basePathSetter interface {
+	setBasePath(string)
+}
+
+// setServiceBasePath overrides the base path of service. When domainVerb is
+// true the path is a format string for the domain, which must hold a single
+// %s verb and no other.
+func setServiceBasePath(service interface{}, path string, domainVerb bool) error {
+	if len(path) < 1 {
+		return NewArgError("path", "cannot be an empty string")
+	}
+	if domainVerb {
+		verbs := strings.ReplaceAll(path, "%%", "")
+		if strings.Count(verbs, "%") != 1 || strings.Count(verbs, "%s") != 1 {
+			return NewArgError("path", "it must contain a single %s verb for the domain")
+		}
+	}
+
+	s, ok := service.(basePathSetter)
+	if !ok {
+		return fmt.Errorf("%T does not support overriding its base path", service)
+	}
+
+	s.setBasePath(path)
+	return nil
+}
+
+// SetDomainsBasePath is a client option for overriding the base path of the
+// domains service, e.g. to point it at a staging path.
+func SetDomainsBasePath(path string) func(*Client) error {
+	return func(c *Client) error {
+		return setServiceBasePath(c.Domains, path, false)
+	}
+}
+
+// SetRackspaceEmailAliasesBasePath is a client option for overriding the base
+// path of the Rackspace Email aliases service. The path must contain a %s verb
+// for the domain.
+func SetRackspaceEmailAliasesBasePath(path string) func(*Client) error {
+	return func(c *Client) error {
+		return setServiceBasePath(c.RackspaceEmailAliases, path, true)
+	}
+}
+
+// SetExchangeBasePath is a client option for overriding the base path of the
+// Exchange mailboxes service. The path must contain a %s verb for the domain.
+func SetExchangeBasePath(path string) func(*Client) error {
+	return func(c *Client) error {
+		return setServiceBasePath(c.Exchange, path, true)
+	}
+}
+
+// SetAcceptLanguage is a client option for setting the Accept-Language header
+// of every request, which selects the language of localized API error
+// messages. The header is not part of the request signature.
+func SetAcceptLanguage(lang string) func(*Client) error {
+	return func(c *Client) error {
+		c.acceptLanguage = lang
+		return nil
+	}
+}
+
+// SetCustomerID is a client option for addressing the domains of customer id
+// rather than those of the customer owning the API keys. A customer ID set on
+// the context with WithCustomerID takes precedence.
+func SetCustomerID(id string) func(*Client) error {
+	return func(c *Client) error {
+		c.customerID = id
+		return nil
+	}
+}
+
+// SetDisabledAliasPlaceholder is a client option for setting the address that
+// RackspaceEmailAliases.Disable makes the sole member of a disabled alias. It
+// defaults to disabled-alias@example.invalid; use an address the account
+// accepts as an alias member and that discards mail.
+func SetDisabledAliasPlaceholder(addr string) func(*Client) error {
+	return func(c *Client) error {
+		if _, err := JoinAliasEmails([]string{addr}); err != nil {
+			return NewArgError("addr", fmt.Sprintf("%q is not an email address", addr))
+		}
+		c.disabledAliasPlaceholder = addr
+		return nil
+	}
+}
+
 // SetDebugHTTP is a client option for setting debugging for HTTP calls.
 func SetDebugHTTP() func(*Client) error {
 	return func(c *Client) error {
@@ -204,6 +673,149 @@ func SetDebugHTTP() func(*Client) error {
 	}
 }
 
+// SetDialer is a client option for setting the dialer used to open
+// connections, e.g. to force IPv4, set dial timeouts or pin a source address.
+// It is ignored when a custom *http.Client was supplied.
+func SetDialer(d *net.Dialer) func(*Client) error {
+	return func(c *Client) error {
+		if d == nil {
+			return NewArgError("dialer", "it cannot be nil")
+		}
+		if c.transport != nil {
+			c.transport.DialContext = d.DialContext
+		}
+		return nil
+	}
+}
+
+// SetProxy is a client option for sending requests through the HTTP proxy at
+// proxyURL. An empty proxyURL uses the proxy from the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables, as the default transport does. Like
+// SetDialer, it is ignored when a custom *http.Client was supplied.
+func SetProxy(proxyURL string) func(*Client) error {
+	return func(c *Client) error {
+		proxy := http.ProxyFromEnvironment
+		if proxyURL != "" {
+			u, err := url.Parse(proxyURL)
+			if err != nil {
+				return err
+			}
+			if u.Scheme == "" || u.Host == "" {
+				return NewArgError("proxyURL", "it must have a scheme and a host")
+			}
+			proxy = http.ProxyURL(u)
+		}
+
+		if c.transport != nil {
+			c.transport.Proxy = proxy
+		}
+		return nil
+	}
+}
+
+// SetForceHTTP1 is a client option for disabling HTTP/2, for proxies that
+// mishandle it. Like SetDialer, it is ignored when a custom *http.Client was
+// supplied.
+func SetForceHTTP1(force bool) func(*Client) error {
+	return func(c *Client) error {
+		if c.transport == nil {
+			return nil
+		}
+
+		if force {
+			// A non-nil, empty TLSNextProto map disables HTTP/2.
+			c.transport.ForceAttemptHTTP2 = false
+			c.transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		} else {
+			c.transport.ForceAttemptHTTP2 = true
+			c.transport.TLSNextProto = nil
+		}
+		return nil
+	}
+}
+
+// SetConnectionPool is a client option for tuning the idle connections kept by
+// the client: maxIdle across all hosts, maxIdlePerHost for each host and how
+// long an idle connection is kept for. Zero means no limit for the counts and
+// no timeout for idleTimeout. Like SetDialer, it is ignored when a custom
+// *http.Client was supplied; configure that client's transport instead.
+func SetConnectionPool(maxIdle, maxIdlePerHost int, idleTimeout time.Duration) func(*Client) error {
+	return func(c *Client) error {
+		if maxIdle < 0 {
+			return NewArgError("maxIdle", "it cannot be negative")
+		}
+		if maxIdlePerHost < 0 {
+			return NewArgError("maxIdlePerHost", "it cannot be negative")
+		}
+		if idleTimeout < 0 {
+			return NewArgError("idleTimeout", "it cannot be negative")
+		}
+
+		if c.transport != nil {
+			c.transport.MaxIdleConns = maxIdle
+			c.transport.MaxIdleConnsPerHost = maxIdlePerHost
+			c.transport.IdleConnTimeout = idleTimeout
+		}
+		return nil
+	}
+}
+
+// SetMetricsHook is a client option for setting a function that is called
+// after every HTTP request with its method, status code and duration. The
+// status is 0 if no response was received. It lets callers feed their own
+// metrics without the package depending on a metrics library.
+func SetMetricsHook(hook func(method string, status int, dur time.Duration)) func(*Client) error {
+	return func(c *Client) error {
+		c.metricsHook = hook
+		return nil
+	}
+}
+
+// SetShowNotFoundNil is a client option for making the Show methods return a
+// nil result and a nil error when the resource does not exist, instead of an
+// *ErrorResponse with a 404 status code. The response is still returned. It
+// is disabled by default.
+func SetShowNotFoundNil(notFoundNil bool) func(*Client) error {
+	return func(c *Client) error {
+		c.showNotFoundNil = notFoundNil
+		return nil
+	}
+}
+
+// SetErrorFormatter is a client option for customizing the Error() string of
+// the *ErrorResponse errors returned by the client, e.g. to show a short
+// message to users while logging the full detail elsewhere. The formatter must
+// not call Error on the *ErrorResponse it is given. A nil formatter restores
+// the default format.
+func SetErrorFormatter(formatter func(*ErrorResponse) string) func(*Client) error {
+	return func(c *Client) error {
+		c.errorFormatter = formatter
+		return nil
+	}
+}
+
+// SetPageAdvance is a client option for changing how the Index methods move
+// from one page to the next. It is only used when the API does not return the
+// next offset itself. A nil advance restores DefaultPageAdvance.
+func SetPageAdvance(advance PageAdvanceFunc) func(*Client) error {
+	return func(c *Client) error {
+		c.pageAdvance = advance
+		return nil
+	}
+}
+
+// SetAutoPaginate is a client option for whether listings follow the next
+// pages until the last one, which is the default. When disabled, Index and
+// IndexWithTotal make a single request for the page selected by their
+// PageOptions. The helpers that cover a whole domain or account, such as
+// EachDomain, Accounts.Limits and DeleteAll, still list every page.
+func SetAutoPaginate(auto bool) func(*Client) error {
+	return func(c *Client) error {
+		c.singlePage = !auto
+		return nil
+	}
+}
+
 // SetGetLimiter is a client option for setting the ratelimiter for GET
 // requests. rps is the requests per second and burst is the number of
 // burst requests allowed.
@@ -224,12 +836,132 @@ func SetPostLimiter(rps float64, burst int) func(*Client) error {
 	}
 }
 
+// showNotFound reports whether err from a Show method should be dropped
+// because the resource does not exist and the client was configured with
+// SetShowNotFoundNil.
+func (c *Client) showNotFound(err error) bool {
+	return c.showNotFoundNil && isNotFound(err)
+}
+
+// resolveDomain returns domain if it is non-empty and the client's default
+// domain otherwise.
+func (c *Client) resolveDomain(domain string) string {
+	if len(domain) < 1 {
+		return c.defaultDomain
+	}
+	return domain
+}
+
+type contextKey int
+
+const (
+	acceptContextKey contextKey = iota
+	idempotencyKeyContextKey
+	rateLimitBypassContextKey
+	cacheBypassContextKey
+	customerIDContextKey
+	existenceCheckContextKey
+	allPagesContextKey
+	jobPollContextKey
+)
+
+// WithIdempotencyKey returns a copy of ctx that makes mutating requests
+// created with it carry key in an Idempotency-Key header. Reuse the same
+// context, and so the same key, when retrying one logical operation so that
+// the attempts can be recognized as duplicates. GET and HEAD requests do not
+// carry the header.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+// WithRateLimitBypass returns a copy of ctx that makes requests sent with it
+// skip the client's rate limiters. It is meant for exceptional use, such as a
+// single urgent call during an incident: bypassed requests still count
+// against the API's own limits, which may answer with 429 Too Many Requests.
+func WithRateLimitBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateLimitBypassContextKey, true)
+}
+
+// WithAccept returns a copy of ctx that makes requests created with it ask for
+// mediaType instead of JSON. Use it with an io.Writer passed to Do to fetch
+// non-JSON representations.
+func WithAccept(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, acceptContextKey, mediaType)
+}
+
+// WithCustomerID returns a copy of ctx that makes requests created with it
+// address the domains of customer id, e.g. for resellers managing several
+// customers with one client. It takes precedence over SetCustomerID.
+func WithCustomerID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, customerIDContextKey, id)
+}
+
+// customerPath returns path scoped to the customer from ctx or, failing that,
+// the client's customer. Only paths under domainsBasePath are rewritten, to
+// the full v1/customers/{id}/domains form. Without a customer ID the short
+// form is kept, which the API resolves to the customer of the API keys.
+func (c *Client) customerPath(ctx context.Context, path string) string {
+	id, _ := ctx.Value(customerIDContextKey).(string)
+	if id == "" {
+		id = c.customerID
+	}
+	if id == "" || !strings.HasPrefix(path, domainsBasePath) {
+		return path
+	}
+
+	rest := path[len(domainsBasePath):]
+	if rest != "" && rest[0] != '/' && rest[0] != '?' {
+		return path
+	}
+	return "v1/customers/" + url.PathEscape(id) + "/domains" + rest
+}
+
+// withAllPages returns a copy of ctx that makes listings follow every page
+// even when SetAutoPaginate is disabled. The helpers that promise to cover a
+// whole domain or account list with it.
+func withAllPages(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allPagesContextKey, true)
+}
+
+// onePage reports whether a listing made with ctx stops after its first
+// page.
+func (c *Client) onePage(ctx context.Context) bool {
+	allPages, _ := ctx.Value(allPagesContextKey).(bool)
+	return c.singlePage && !allPages
+}
+
+// AllowGET reports whether a GET request could be sent now without waiting on
+// the rate limiter. Unlike Do, it never blocks, so callers can decide for
+// themselves whether to queue more work. Checking does not use up capacity.
+func (c *Client) AllowGET() bool {
+	return allow(c.getLimiter)
+}
+
+// AllowMutate reports whether a PUT, POST or DELETE request could be sent now
+// without waiting on the rate limiter. Unlike Do, it never blocks, so callers
+// can decide for themselves whether to queue more work. Checking does not use
+// up capacity.
+func (c *Client) AllowMutate() bool {
+	return allow(c.putPostDeleteLimiter)
+}
+
+// allow is a non-consuming form of rate.Limiter.Allow: the token reserved for
+// the check is handed back straight away.
+func allow(l *rate.Limiter) bool {
+	now := time.Now()
+	r := l.ReserveN(now, 1)
+	defer r.CancelAt(now)
+	return r.OK() && r.DelayFrom(now) == 0
+}
+
 // NewRequest creates an API request. A relative URL can be provided in
 // urlStr, which will be resolved to the BaseURL of the Client. Relative URLs
 // should always be specified without a preceding slash. If specified, the
-// map body is rendered as application/x-www-form-urlencoded.
+// map body is rendered as application/x-www-form-urlencoded. The Accept
+// header defaults to JSON and can be overridden with WithAccept. Domain paths
+// are scoped to the customer set with WithCustomerID or SetCustomerID.
 func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body map[string]string) (*http.Request, error) {
-	rel, err := url.Parse(urlStr)
+	rel, err := url.Parse(c.customerPath(ctx, urlStr))
 	if err != nil {
 		return nil, err
 	}
@@ -248,34 +980,96 @@ func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body map
 		return nil, err
 	}
 
-	if method == "POST" {
+	if method == http.MethodPost || method == http.MethodPut {
 		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	} else {
 		req.Header.Add("Content-Type", mediaType)
 	}
-	req.Header.Add("Accept", mediaType)
+	accept := mediaType
+	if v, ok := ctx.Value(acceptContextKey).(string); ok && v != "" {
+		accept = v
+	}
+	req.Header.Add("Accept", accept)
 	req.Header.Add("User-Agent", c.UserAgent)
+	if c.forwardedFor != "" {
+		req.Header.Add("X-Forwarded-For", c.forwardedFor)
+	}
+	if c.acceptLanguage != "" {
+		req.Header.Add("Accept-Language", c.acceptLanguage)
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		if key, ok := ctx.Value(idempotencyKeyContextKey).(string); ok && key != "" {
+			req.Header.Add(idempotencyKeyHeader, key)
+		}
+	}
 
-	c.sign(req)
+	if !c.unsigned && !c.offline {
+		if c.userKey == "" || c.secretKey == "" {
+			return nil, ErrMissingCredentials
+		}
+		c.sign(req)
+	}
 
 	return req, nil
 }
 
+// get issues a GET request for path with the query options in opt and decodes
+// the response into root.
+func (c *Client) get(ctx context.Context, path string, opt interface{}, root interface{}) (*Response, error) {
+	if opt != nil {
+		var err error
+		path, err = addOptions(path, opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.NewRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(ctx, req, root)
+}
+
+// SignedRequest returns a fully built and signed request for path without
+// sending it, e.g. to hand it to another tool while debugging. The
+// X-Api-Signature header embeds the current time and the API rejects it once
+// it is too old, so the request should be used promptly.
+func (c *Client) SignedRequest(ctx context.Context, method, path string, body map[string]string) (*http.Request, error) {
+	return c.NewRequest(ctx, method, path, body)
+}
+
 func (c *Client) sign(req *http.Request) {
-	ua := req.Header.Get("User-Agent")
-	ts := time.Now().Format("20060102150405")
+	// The API checks the timestamp against its own clock in UTC.
+	sig := BuildSignature(c.userKey, c.secretKey, req.Header.Get("User-Agent"), c.clock.Now().UTC())
+	req.Header.Add("X-Api-Signature", sig)
+}
+
+// BuildSignature returns the X-Api-Signature header value the client sends
+// for a request made at t with the user agent ua. It is exported so that
+// signatures can be computed and compared independently when debugging 401
+// responses. The value is "userKey:timestamp:hash", where the timestamp is t
+// formatted as YYYYMMDDhhmmss in t's location and the hash is the base64
+// encoded SHA-1 of the user key, user agent, timestamp and secret key.
+func BuildSignature(userKey, secretKey, ua string, t time.Time) string {
+	ts := t.Format("20060102150405")
 
 	hasher := sha1.New()
-	io.WriteString(hasher, fmt.Sprintf("%s%s%s%s", c.userKey, ua, ts, c.secretKey))
+	io.WriteString(hasher, fmt.Sprintf("%s%s%s%s", userKey, ua, ts, secretKey))
 
 	b64 := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
-	sig := fmt.Sprintf("%s:%s:%s", c.userKey, ts, b64)
+	return fmt.Sprintf("%s:%s:%s", userKey, ts, b64)
+}
 
-	req.Header.Add("X-Api-Signature", sig)
+type responseRoot struct {
+	RequestID string   `json:"request_id"`
+	Warnings  []string `json:"warnings"`
 }
 
 func newResponse(r *http.Response) *Response {
 	response := Response{Response: r}
+	response.RequestID = r.Header.Get(requestIDHeader)
 
 	return &response
 }
@@ -285,65 +1079,186 @@ func newResponse(r *http.Response) *Response {
 // error if an API error has occurred. If v implements the io.Writer interface,
 // the raw response will be written to v, without attempting to decode it.
 func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
-	dump, err := httputil.DumpRequest(req, true)
 	if c.debugHTTP {
-		fmt.Fprintf(os.Stderr, "Req: %s\n", string(dump))
-	}
-
-	// Rate limiting
-	switch req.Method {
-	case "GET":
-		if err := c.getLimiter.Wait(ctx); err != nil {
-			return nil, err
-		}
-	default:
-		if err := c.putPostDeleteLimiter.Wait(ctx); err != nil {
+		dump, err := httputil.DumpRequest(req, true)
+		if err != nil {
 			return nil, err
 		}
+		fmt.Fprintf(os.Stderr, "Req: %s\n", string(dump))
 	}
 
-	resp, err := DoRequestWithClient(ctx, c.client, req)
+	var resp *http.Response
+	var err error
+	bypass, _ := ctx.Value(cacheBypassContextKey).(bool)
+	if c.cache != nil && req.Method == http.MethodGet && !bypass {
+		resp, err = c.cache.do(getKey(req), req, c.clock.Now(), func() (*http.Response, error) {
+			return c.fetch(ctx, req)
+		})
+	} else {
+		resp, err = c.fetch(ctx, req)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	defer func() {
+		// Drain whatever was left unread, e.g. after a decode error, so
+		// the transport can reuse the connection.
+		io.Copy(ioutil.Discard, resp.Body)
 		if rerr := resp.Body.Close(); err == nil {
 			err = rerr
 		}
 	}()
 
-	resDump, err := httputil.DumpResponse(resp, true)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	if c.debugHTTP {
+		resDump, err := httputil.DumpResponse(resp, true)
+		if err != nil {
+			return nil, err
+		}
 		fmt.Fprintf(os.Stderr, "Resp: %s\n", resDump)
 	}
 
 	response := newResponse(resp)
 
-	err = CheckResponse(resp)
+	err = checkResponse(resp, c.clock.Now())
 	if err != nil {
+		if errorResponse, ok := err.(*ErrorResponse); ok {
+			errorResponse.formatter = c.errorFormatter
+		}
+		return response, err
+	}
+
+	if w, ok := v.(io.Writer); ok {
+		_, err = io.Copy(w, resp.Body)
+		if err != nil {
+			return nil, err
+		}
 		return response, err
 	}
 
+	var buf bytes.Buffer
 	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			_, err = io.Copy(w, resp.Body)
-			if err != nil {
+		target := v
+		raw, keepRaw := v.(*withRaw)
+		if keepRaw {
+			target = raw.v
+		}
+
+		err = json.NewDecoder(io.TeeReader(resp.Body, &buf)).Decode(target)
+		if err != nil {
+			io.CopyN(&buf, resp.Body, maxBodySnippet)
+			return nil, fmt.Errorf("decoding response (body: %q): %w", bodySnippet(buf.Bytes()), err)
+		}
+
+		if keepRaw {
+			if _, err = io.Copy(&buf, resp.Body); err != nil {
 				return nil, err
 			}
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
+			raw.raw = append(json.RawMessage(nil), bytes.TrimSpace(buf.Bytes())...)
+		}
+	} else if _, err = io.Copy(&buf, resp.Body); err != nil {
+		return nil, err
+	}
+
+	root := new(responseRoot)
+	if json.NewDecoder(&buf).Decode(root) == nil {
+		if response.RequestID == "" {
+			response.RequestID = root.RequestID
+		}
+		response.Warnings = root.Warnings
+	}
+
+	return response, err
+}
+
+// bodySnippet returns the start of a response body for error messages, cut
+// at maxBodySnippet bytes.
+func bodySnippet(body []byte) string {
+	if len(body) > maxBodySnippet {
+		return string(body[:maxBodySnippet]) + "..."
+	}
+	return string(body)
+}
+
+// getKey identifies the GETs that can share a response.
+func getKey(req *http.Request) string {
+	return req.URL.String() + "\n" + req.Header.Get("Accept")
+}
+
+// fetch sends req, sharing the request with identical in-flight requests
+// when SetCoalesceGETs or SetDedupeMutations is enabled.
+func (c *Client) fetch(ctx context.Context, req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet:
+		if c.getGroup != nil {
+			return c.getGroup.do(getKey(req), func() (*http.Response, error) {
+				return c.send(ctx, req)
+			})
+		}
+	case http.MethodPost, http.MethodPut, http.MethodDelete:
+		if c.mutationGroup != nil {
+			key, err := mutationKey(req)
 			if err != nil {
 				return nil, err
 			}
+			return c.mutationGroup.do(key, func() (*http.Response, error) {
+				return c.send(ctx, req)
+			})
 		}
 	}
+	return c.send(ctx, req)
+}
 
-	return response, err
+// withRaw is passed to Do in place of v to decode the response body into v
+// and also keep the body as it was received in raw.
+type withRaw struct {
+	v   interface{}
+	raw json.RawMessage
+}
+
+// send waits on the rate limiter for the request's method and submits it.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.offline {
+		return c.offlineResponse(ctx, req), nil
+	}
+
+	limiter := c.limiter(req)
+	if bypass, _ := ctx.Value(rateLimitBypassContextKey).(bool); !bypass {
+		// The domain's own limit comes on top of the account wide one.
+		if dl := c.domainLimiter(req); dl != nil {
+			if err := dl.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("rate limiter wait: %w", err)
+			}
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limiter wait: %w", err)
+		}
+	}
+
+	start := c.clock.Now()
+	resp, err := DoRequestWithClient(ctx, c.client, req)
+	if c.metricsHook != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.metricsHook(req.Method, status, c.clock.Now().Sub(start))
+	}
+	if c.adaptive != nil && resp != nil {
+		c.adaptive.adjust(limiter, resp.StatusCode)
+	}
+
+	return resp, err
+}
+
+// limiter returns the rate limiter for the method of req.
+func (c *Client) limiter(req *http.Request) *rate.Limiter {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return c.getLimiter
+	default:
+		return c.putPostDeleteLimiter
+	}
 }
 
 // DoRequest submits an HTTP request.
@@ -361,30 +1276,72 @@ func DoRequestWithClient(ctx context.Context, client *http.Client, req *http.Req
 // present. A response is considered an error if it has a status code outside
 // the 200 range. API error responses are expected to have either no response
 // body, or a JSON response body that maps to ErrorResponse. Any other
-// response body will be silently ignored.
+// response body will be silently ignored. A 503 without a JSON body is
+// returned as a *ServiceUnavailableError.
 func CheckResponse(r *http.Response) error {
+	return checkResponse(r, time.Now())
+}
+
+// checkResponse implements CheckResponse, using now to resolve Retry-After
+// dates.
+func checkResponse(r *http.Response, now time.Time) error {
 	if c := r.StatusCode; c >= 200 && c <= 299 {
 		return nil
 	}
 
-	errorResponse := &ErrorResponse{Response: r}
 	data, err := ioutil.ReadAll(r.Body)
+
+	// During maintenance windows the API answers with an HTML page rather
+	// than a JSON error.
+	if r.StatusCode == http.StatusServiceUnavailable && decodeLeadingJSON(data, new(json.RawMessage)) != nil {
+		return &ServiceUnavailableError{
+			Response:   r,
+			RetryAfter: parseRetryAfter(r.Header.Get("Retry-After"), now),
+		}
+	}
+
+	errorResponse := &ErrorResponse{Response: r}
 	if err == nil && len(data) > 0 {
-		err := json.Unmarshal(data, errorResponse)
+		var err error
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = decodeLeadingJSON(data, &errorResponse.Errors)
+		} else {
+			err = decodeLeadingJSON(data, errorResponse)
+		}
 		if err != nil {
 			errorResponse.Message = string(data)
 		}
+		if errorResponse.Message == "" && len(errorResponse.Errors) > 0 {
+			errorResponse.Message = fmt.Sprintf("%d item errors", len(errorResponse.Errors))
+		}
 	}
 
 	return errorResponse
 }
 
-// Error returns a string representation of an API error
+// decodeLeadingJSON decodes the JSON value at the start of data into v,
+// ignoring anything that follows it, since error bodies are not always
+// terminated cleanly.
+func decodeLeadingJSON(data []byte, v interface{}) error {
+	return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Error returns a string representation of an API error, using the client's
+// error formatter if one was set with SetErrorFormatter.
 func (r *ErrorResponse) Error() string {
+	if r.formatter != nil {
+		return r.formatter(r)
+	}
+
+	status := strconv.Itoa(r.Response.StatusCode)
+	if text := http.StatusText(r.Response.StatusCode); text != "" {
+		status = fmt.Sprintf("%s %s", status, text)
+	}
+
 	if r.RequestID != "" {
-		return fmt.Sprintf("%v %v: %d (request %q) %v",
-			r.Response.Request.Method, r.Response.Request.URL, r.Response.StatusCode, r.RequestID, r.Message)
+		return fmt.Sprintf("%v %v: %s (request %q) %v",
+			r.Response.Request.Method, r.Response.Request.URL, status, r.RequestID, r.Message)
 	}
-	return fmt.Sprintf("%v %v: %d %v",
-		r.Response.Request.Method, r.Response.Request.URL, r.Response.StatusCode, r.Message)
+	return fmt.Sprintf("%v %v: %s %v",
+		r.Response.Request.Method, r.Response.Request.URL, status, r.Message)
 }
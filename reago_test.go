@@ -21,12 +21,25 @@
 package reago
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"net/url"
+	"os"
 	"reflect"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
 var (
@@ -43,6 +56,8 @@ func setup() {
 	client = NewClient(nil)
 	url, _ := url.Parse(server.URL)
 	client.BaseURL = url
+	client.userKey = "userid"
+	client.secretKey = "hunter2"
 }
 
 func teardown() {
@@ -57,8 +72,10 @@ func testMethod(t *testing.T, r *http.Request, expected string) {
 
 func testClientServices(t *testing.T, c *Client) {
 	services := []string{
+		"Accounts",
 		"RackspaceEmailAliases",
 		"Domains",
+		"Exchange",
 	}
 
 	cp := reflect.ValueOf(c)
@@ -109,6 +126,33 @@ func Test_New(t *testing.T) {
 	testClientDefaults(t, c)
 }
 
+func Test_NewWithCredentials(t *testing.T) {
+	c, err := NewWithCredentials("userid", "hunter2", SetUserAgent("test_ua"))
+
+	if err != nil {
+		t.Fatalf("NewWithCredentials(): %v", err)
+	}
+
+	if c.userKey != "userid" {
+		t.Errorf("NewWithCredentials userKey = %v, expected %v", c.userKey, "userid")
+	}
+	if c.secretKey != "hunter2" {
+		t.Errorf("NewWithCredentials secretKey = %v, expected %v", c.secretKey, "hunter2")
+	}
+	if c.UserAgent != "test_ua" {
+		t.Errorf("NewWithCredentials UserAgent = %v, expected %v", c.UserAgent, "test_ua")
+	}
+}
+
+func Test_NewWithCredentials_EmptyKeys(t *testing.T) {
+	if _, err := NewWithCredentials("", "hunter2"); err == nil {
+		t.Errorf("NewWithCredentials() should have returned an error for an empty user key")
+	}
+	if _, err := NewWithCredentials("userid", ""); err == nil {
+		t.Errorf("NewWithCredentials() should have returned an error for an empty secret key")
+	}
+}
+
 func Test_New_OptionSetBaseURL(t *testing.T) {
 	baseURL := "https://test.com/api"
 	c, err := New(nil, SetBaseURL(baseURL))
@@ -122,6 +166,15 @@ func Test_New_OptionSetBaseURL(t *testing.T) {
 	}
 }
 
+func Test_New_OptionSetBaseURL_Invalid(t *testing.T) {
+	for _, baseURL := range []string{"", "api.emailsrvr.com/", "ftp://api.emailsrvr.com/", "https://"} {
+		_, err := New(nil, SetBaseURL(baseURL))
+		if err == nil {
+			t.Errorf("New() should have returned an error for base URL %q", baseURL)
+		}
+	}
+}
+
 func Test_New_OptionSetUserAgent(t *testing.T) {
 	userAgent := "test_ua"
 	c, err := New(nil, SetUserAgent(userAgent))
@@ -135,6 +188,21 @@ func Test_New_OptionSetUserAgent(t *testing.T) {
 	}
 }
 
+func Test_New_OptionSetUserAgent_Invalid(t *testing.T) {
+	for _, ua := range []string{"test_ua\r\nX-Injected: 1", "test\x00ua", "test_ua/\u00e9", " test_ua", "test_ua\t"} {
+		if _, err := New(nil, SetUserAgent(ua)); err == nil {
+			t.Errorf("New() should have returned an error for user agent %q", ua)
+		}
+	}
+}
+
+func Test_New_OptionSetUserAgent_Valid(t *testing.T) {
+	ua := "mytool/1.2 (+https://example.com; ops@example.com) reago/1.0"
+	if _, err := New(nil, SetUserAgent(ua)); err != nil {
+		t.Errorf("New() returned error for user agent %q: %v", ua, err)
+	}
+}
+
 func Test_New_OptionSetUserKey(t *testing.T) {
 	userKey := "userid"
 	c, err := New(nil, SetUserKey(userKey))
@@ -172,3 +240,1109 @@ func Test_New_OptionDebug(t *testing.T) {
 		t.Errorf("NewClient debugHTTP = %v, expected %v", c.debugHTTP, true)
 	}
 }
+
+func TestDo_RequestIDFromBody(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"request_id": "abc123", "domain": {"name":"foo.com"}}`)
+	})
+
+	_, resp, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	if resp.RequestID != "abc123" {
+		t.Errorf("Response RequestID = %v, expected %v", resp.RequestID, "abc123")
+	}
+}
+
+func TestDo_Warnings(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"warnings": ["alias created but some addresses were skipped"]}`)
+	})
+
+	resp, err := client.RackspaceEmailAliases.Add(ctx, "foo.com", "bar", []string{"a@foo.com"})
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Add returned error: %v", err)
+	}
+
+	expected := []string{"alias created but some addresses were skipped"}
+	if !reflect.DeepEqual(resp.Warnings, expected) {
+		t.Errorf("Response Warnings = %v, expected %v", resp.Warnings, expected)
+	}
+}
+
+func TestDo_WarningsWithTarget(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}, "warnings": ["w1", "w2"]}`)
+	})
+
+	_, resp, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	expected := []string{"w1", "w2"}
+	if !reflect.DeepEqual(resp.Warnings, expected) {
+		t.Errorf("Response Warnings = %v, expected %v", resp.Warnings, expected)
+	}
+}
+
+func TestDo_DecodeErrorSnippet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html>Service Unavailable</html>`)
+	})
+
+	_, _, err := client.Domains.Show(ctx, "foo.com")
+	if err == nil {
+		t.Fatal("Domains.Show should have returned an error for a malformed body")
+	}
+
+	if !strings.Contains(err.Error(), `"<html>Service Unavailable</html>"`) {
+		t.Errorf("Error %q should contain the response body", err)
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("Error %v should wrap a *json.SyntaxError", err)
+	}
+}
+
+func TestDo_DecodeErrorSnippetTruncated(t *testing.T) {
+	setup()
+	defer teardown()
+
+	body := "x" + strings.Repeat("y", 2*maxBodySnippet)
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	})
+
+	_, _, err := client.Domains.Show(ctx, "foo.com")
+	if err == nil {
+		t.Fatal("Domains.Show should have returned an error for a malformed body")
+	}
+
+	expected := fmt.Sprintf("%q", body[:maxBodySnippet]+"...")
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Error %q should contain the truncated body %s", err, expected)
+	}
+}
+
+func TestDo_RequestIDFromHeader(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "def456")
+	})
+
+	resp, err := client.RackspaceEmailAliases.Delete(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RackspaceEmailAliases.Delete returned error: %v", err)
+	}
+
+	if resp.RequestID != "def456" {
+		t.Errorf("Response RequestID = %v, expected %v", resp.RequestID, "def456")
+	}
+}
+
+func Test_New_OptionSetDefaultDomain(t *testing.T) {
+	c, err := New(nil, SetDefaultDomain("foo.com"))
+
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.defaultDomain != "foo.com" {
+		t.Errorf("NewClient defaultDomain = %v, expected %v", c.defaultDomain, "foo.com")
+	}
+}
+
+func TestDefaultDomain_Fallback(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.defaultDomain = "foo.com"
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+	})
+
+	_, err := client.RackspaceEmailAliases.Delete(ctx, "", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Delete returned error: %v", err)
+	}
+}
+
+func TestDefaultDomain_Override(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.defaultDomain = "foo.com"
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("RackspaceEmailAliases.Delete should not have used the default domain")
+	})
+	mux.HandleFunc("/v1/domains/baz.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+	})
+
+	_, err := client.RackspaceEmailAliases.Delete(ctx, "baz.com", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Delete returned error: %v", err)
+	}
+}
+
+func TestDefaultDomain_Unset(t *testing.T) {
+	setup()
+	defer teardown()
+
+	_, err := client.RackspaceEmailAliases.Delete(ctx, "", "bar")
+	if err == nil {
+		t.Errorf("RackspaceEmailAliases.Delete should have returned an error without a domain")
+	}
+}
+
+func Test_New_OptionSetForwardedForHeader_Invalid(t *testing.T) {
+	_, err := New(nil, SetForwardedForHeader("not-an-ip"))
+	if err == nil {
+		t.Errorf("New() should have returned an error for an invalid IP")
+	}
+}
+
+func TestNewRequest_ForwardedForHeader(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetForwardedForHeader("192.0.2.10")(client); err != nil {
+		t.Fatalf("SetForwardedForHeader(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("X-Forwarded-For"); v != "192.0.2.10" {
+			t.Errorf("Request X-Forwarded-For = %v, expected %v", v, "192.0.2.10")
+		}
+		if r.Header.Get("X-Api-Signature") == "" {
+			t.Errorf("Request X-Api-Signature should be set")
+		}
+	})
+
+	_, err := client.RackspaceEmailAliases.Delete(ctx, "foo.com", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Delete returned error: %v", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if size := r.URL.Query().Get("size"); size != "10" {
+			t.Errorf("Request size = %v, expected %v", size, 10)
+		}
+		fmt.Fprint(w, `{"domains": [{"name":"foo.com"}]}`)
+	})
+
+	root := new(domainsRoot)
+	_, err := client.get(ctx, domainsBasePath, &PageOptions{Size: 10}, root)
+	if err != nil {
+		t.Fatalf("get returned error: %v", err)
+	}
+
+	expected := []Domain{{Name: "foo.com"}}
+	if !reflect.DeepEqual(root.Domains, expected) {
+		t.Errorf("get returned %+v, expected %+v", root.Domains, expected)
+	}
+}
+
+func TestGet_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, resp, err := client.Domains.Show(ctx, "foo.com")
+	if !isNotFound(err) {
+		t.Errorf("Domains.Show returned %v, expected a not found error", err)
+	}
+	if resp == nil || resp.Response.StatusCode != http.StatusNotFound {
+		t.Errorf("Domains.Show should have returned the 404 response")
+	}
+}
+
+func TestCheckResponse_ItemErrors(t *testing.T) {
+	bodies := []string{
+		`[{"item": "foo", "message": "invalid"}, {"item": "bar", "message": "exists"}]`,
+		`{"message": "2 item errors", "errors": [{"item": "foo", "message": "invalid"}, {"item": "bar", "message": "exists"}]}`,
+	}
+
+	for _, body := range bodies {
+		res := &http.Response{
+			Request:    &http.Request{},
+			StatusCode: http.StatusBadRequest,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}
+
+		err := CheckResponse(res).(*ErrorResponse)
+
+		expected := []ItemError{
+			{Item: "foo", Message: "invalid"},
+			{Item: "bar", Message: "exists"},
+		}
+		if !reflect.DeepEqual(err.Errors, expected) {
+			t.Errorf("CheckResponse Errors = %+v, expected %+v", err.Errors, expected)
+		}
+		if err.Message != "2 item errors" {
+			t.Errorf("CheckResponse Message = %v, expected %v", err.Message, "2 item errors")
+		}
+	}
+}
+
+func TestCheckResponse_TrailingData(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusBadRequest,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "Invalid alias", "request_id": "abc123"}` + "\nstray text")),
+	}
+
+	err := CheckResponse(res).(*ErrorResponse)
+
+	if err.Message != "Invalid alias" {
+		t.Errorf("CheckResponse Message = %q, expected %q", err.Message, "Invalid alias")
+	}
+	if err.RequestID != "abc123" {
+		t.Errorf("CheckResponse RequestID = %q, expected %q", err.RequestID, "abc123")
+	}
+}
+
+func TestCheckResponse_PlainText(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusBadRequest,
+		Body:       ioutil.NopCloser(strings.NewReader("Bad Request")),
+	}
+
+	err := CheckResponse(res).(*ErrorResponse)
+
+	if err.Message != "Bad Request" {
+		t.Errorf("CheckResponse Message = %q, expected %q", err.Message, "Bad Request")
+	}
+}
+
+func TestResponse_Helpers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Custom", "value")
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+	mux.HandleFunc("/v1/domains/bar.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, resp, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+	if !resp.Is2xx() {
+		t.Errorf("Is2xx() = false, expected true")
+	}
+	if resp.Code() != http.StatusOK {
+		t.Errorf("Code() = %d, expected %d", resp.Code(), http.StatusOK)
+	}
+	if v := resp.HeaderValue("x-custom"); v != "value" {
+		t.Errorf("HeaderValue() = %q, expected %q", v, "value")
+	}
+	if v := resp.HeaderValue("X-Missing"); v != "" {
+		t.Errorf("HeaderValue() = %q, expected an empty string", v)
+	}
+
+	_, resp, _ = client.Domains.Show(ctx, "bar.com")
+	if resp.Is2xx() {
+		t.Errorf("Is2xx() = true, expected false for a 404")
+	}
+	if resp.Code() != http.StatusNotFound {
+		t.Errorf("Code() = %d, expected %d", resp.Code(), http.StatusNotFound)
+	}
+}
+
+func TestResponse_HelpersNil(t *testing.T) {
+	var resp *Response
+	if resp.Is2xx() || resp.Code() != 0 || resp.HeaderValue("X-Request-Id") != "" {
+		t.Errorf("Helpers on a nil Response should return zero values")
+	}
+}
+
+func TestSetErrorFormatter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	formatter := func(r *ErrorResponse) string {
+		return fmt.Sprintf("[%s] %s", r.RequestID, r.Message)
+	}
+	if err := SetErrorFormatter(formatter)(client); err != nil {
+		t.Fatalf("SetErrorFormatter(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Domain not found", "request_id": "abc123"}`)
+	})
+
+	_, _, err := client.Domains.Show(ctx, "foo.com")
+	if err == nil {
+		t.Fatalf("Domains.Show should have returned an error")
+	}
+
+	if expected := "[abc123] Domain not found"; err.Error() != expected {
+		t.Errorf("Error() = %q, expected %q", err.Error(), expected)
+	}
+	if !isNotFound(err) {
+		t.Errorf("Domains.Show returned %v, expected a not found error", err)
+	}
+}
+
+func TestErrorResponse_Error(t *testing.T) {
+	u, _ := url.Parse("https://api.emailsrvr.com/v1/domains/foo.com")
+	res := &http.Response{
+		Request:    &http.Request{Method: http.MethodGet, URL: u},
+		StatusCode: http.StatusNotFound,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "Domain not found"}`)),
+	}
+
+	err := CheckResponse(res)
+
+	expected := "GET https://api.emailsrvr.com/v1/domains/foo.com: 404 Not Found Domain not found"
+	if err.Error() != expected {
+		t.Errorf("Error() = %q, expected %q", err.Error(), expected)
+	}
+}
+
+func TestErrorResponse_Error_UnknownStatus(t *testing.T) {
+	u, _ := url.Parse("https://api.emailsrvr.com/v1/domains/foo.com")
+	res := &http.Response{
+		Request:    &http.Request{Method: http.MethodGet, URL: u},
+		StatusCode: 499,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "oops"}`)),
+	}
+
+	err := CheckResponse(res)
+
+	expected := "GET https://api.emailsrvr.com/v1/domains/foo.com: 499 oops"
+	if err.Error() != expected {
+		t.Errorf("Error() = %q, expected %q", err.Error(), expected)
+	}
+}
+
+func TestDo_ConnectionReuse(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	// A malformed body with plenty of trailing data that the decoder will
+	// not read.
+	body := "x" + strings.Repeat(" ", 1024*1024)
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	})
+
+	reused := 0
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				reused++
+			}
+		},
+	}
+	traceCtx := httptrace.WithClientTrace(ctx, trace)
+
+	calls := 5
+	for i := 0; i < calls; i++ {
+		_, _, err := client.Domains.Show(traceCtx, "foo.com")
+		if err == nil {
+			t.Fatalf("Domains.Show should have returned a decode error")
+		}
+	}
+
+	if reused != calls-1 {
+		t.Errorf("Connections reused %d times, expected %d", reused, calls-1)
+	}
+}
+
+func TestSetDialer(t *testing.T) {
+	setup()
+	defer teardown()
+
+	dials := 0
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			dials++
+			return nil
+		},
+	}
+	if err := SetDialer(dialer)(client); err != nil {
+		t.Fatalf("SetDialer(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	_, _, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+
+	if dials != 1 {
+		t.Errorf("Dialer was used %d times, expected 1", dials)
+	}
+}
+
+func TestSetDialer_CustomClient(t *testing.T) {
+	httpClient := &http.Client{}
+	c, err := New(httpClient, SetDialer(&net.Dialer{}))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.client != httpClient || httpClient.Transport != nil {
+		t.Errorf("SetDialer should not modify a custom HTTP client")
+	}
+}
+
+func TestSetDialer_Nil(t *testing.T) {
+	if _, err := New(nil, SetDialer(nil)); err == nil {
+		t.Errorf("SetDialer should have returned an error for a nil dialer")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewClient_WrappedDefaultTransport(t *testing.T) {
+	saved := http.DefaultTransport
+	defer func() { http.DefaultTransport = saved }()
+	http.DefaultTransport = roundTripperFunc(saved.RoundTrip)
+
+	c, err := New(nil, SetDialer(&net.Dialer{}), SetForceHTTP1(true))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.transport != nil {
+		t.Errorf("NewClient should not have its own transport when DefaultTransport is wrapped")
+	}
+	if c.client == nil || c.client.Transport != nil {
+		t.Errorf("NewClient should fall back to an HTTP client using DefaultTransport")
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	c, err := New(nil, SetProxy("http://proxy.example.com:3128"))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, defaultBaseURL, nil)
+	proxy, err := c.transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("Proxy returned error: %v", err)
+	}
+	if proxy == nil || proxy.String() != "http://proxy.example.com:3128" {
+		t.Errorf("Proxy = %v, expected %v", proxy, "http://proxy.example.com:3128")
+	}
+}
+
+func TestSetProxy_Environment(t *testing.T) {
+	c, err := New(nil, SetProxy(""))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.transport.Proxy == nil {
+		t.Errorf("SetProxy should fall back to the proxy from the environment")
+	}
+}
+
+func TestSetProxy_Invalid(t *testing.T) {
+	if _, err := New(nil, SetProxy("proxy.example.com")); err == nil {
+		t.Errorf("SetProxy should have returned an error for a URL without a scheme")
+	}
+}
+
+// writeKeyFile writes contents to a temporary file and returns its path.
+func writeKeyFile(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("", "reago-key")
+	if err != nil {
+		t.Fatalf("TempFile(): %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("WriteString(): %v", err)
+	}
+	return f.Name()
+}
+
+func TestSetSecretKeyFromFile(t *testing.T) {
+	uk := writeKeyFile(t, "userid")
+	defer os.Remove(uk)
+	sk := writeKeyFile(t, "hunter2")
+	defer os.Remove(sk)
+
+	c, err := New(nil, SetUserKeyFromFile(uk), SetSecretKeyFromFile(sk))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.userKey != "userid" {
+		t.Errorf("userKey = %q, expected %q", c.userKey, "userid")
+	}
+	if c.secretKey != "hunter2" {
+		t.Errorf("secretKey = %q, expected %q", c.secretKey, "hunter2")
+	}
+}
+
+func TestSetSecretKeyFromFile_TrailingNewline(t *testing.T) {
+	sk := writeKeyFile(t, "hunter2\n")
+	defer os.Remove(sk)
+
+	c, err := New(nil, SetSecretKeyFromFile(sk))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.secretKey != "hunter2" {
+		t.Errorf("secretKey = %q, expected %q", c.secretKey, "hunter2")
+	}
+}
+
+func TestSetSecretKeyFromFile_Missing(t *testing.T) {
+	sk := writeKeyFile(t, "hunter2")
+	os.Remove(sk)
+
+	if _, err := New(nil, SetSecretKeyFromFile(sk)); err == nil {
+		t.Errorf("SetSecretKeyFromFile should have returned an error for a missing file")
+	}
+	if _, err := New(nil, SetUserKeyFromFile(sk)); err == nil {
+		t.Errorf("SetUserKeyFromFile should have returned an error for a missing file")
+	}
+}
+
+func TestSetSecretKeyFromFile_Empty(t *testing.T) {
+	sk := writeKeyFile(t, " \n")
+	defer os.Remove(sk)
+
+	if _, err := New(nil, SetSecretKeyFromFile(sk)); err == nil {
+		t.Errorf("SetSecretKeyFromFile should have returned an error for an empty file")
+	}
+}
+
+func TestSetForceHTTP1(t *testing.T) {
+	c, err := New(nil, SetForceHTTP1(true))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.transport.ForceAttemptHTTP2 {
+		t.Errorf("ForceAttemptHTTP2 should be false")
+	}
+	if c.transport.TLSNextProto == nil || len(c.transport.TLSNextProto) != 0 {
+		t.Errorf("TLSNextProto = %v, expected an empty map", c.transport.TLSNextProto)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 1 {
+			t.Errorf("Request protocol = %s, expected HTTP/1", r.Proto)
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	c.transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestSetForceHTTP1_CustomClient(t *testing.T) {
+	httpClient := &http.Client{}
+	c, err := New(httpClient, SetForceHTTP1(true))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.client != httpClient || httpClient.Transport != nil {
+		t.Errorf("SetForceHTTP1 should not modify a custom HTTP client")
+	}
+}
+
+func TestSetConnectionPool(t *testing.T) {
+	c, err := New(nil, SetConnectionPool(200, 20, time.Minute))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if c.transport.MaxIdleConns != 200 {
+		t.Errorf("MaxIdleConns = %d, expected %d", c.transport.MaxIdleConns, 200)
+	}
+	if c.transport.MaxIdleConnsPerHost != 20 {
+		t.Errorf("MaxIdleConnsPerHost = %d, expected %d", c.transport.MaxIdleConnsPerHost, 20)
+	}
+	if c.transport.IdleConnTimeout != time.Minute {
+		t.Errorf("IdleConnTimeout = %v, expected %v", c.transport.IdleConnTimeout, time.Minute)
+	}
+	if http.DefaultTransport.(*http.Transport).MaxIdleConns == 200 {
+		t.Errorf("SetConnectionPool should not modify http.DefaultTransport")
+	}
+}
+
+func TestSetConnectionPool_Invalid(t *testing.T) {
+	if _, err := New(nil, SetConnectionPool(-1, 0, 0)); err == nil {
+		t.Errorf("SetConnectionPool should have returned an error for a negative maxIdle")
+	}
+}
+
+func TestNewRequest_MissingCredentials(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No request should have been sent without credentials")
+	})
+
+	for _, keys := range [][2]string{{"", ""}, {"userid", ""}, {"", "hunter2"}} {
+		client.userKey, client.secretKey = keys[0], keys[1]
+
+		_, _, err := client.Domains.Show(ctx, "foo.com")
+		if !errors.Is(err, ErrMissingCredentials) {
+			t.Errorf("Domains.Show returned %v, expected %v", err, ErrMissingCredentials)
+		}
+	}
+}
+
+func TestBuildSignature(t *testing.T) {
+	tests := []struct {
+		userKey, secretKey, ua string
+		t                      time.Time
+		expected               string
+	}{
+		{
+			// The example from the Rackspace Email API documentation
+			userKey:   "eGbq9/2hcZsRlr1JV1Pi",
+			secretKey: "QHOvchm/40czXhJ1OxfxK7jDHr3t",
+			ua:        "Rackspace Management Interface",
+			t:         time.Date(2001, 3, 8, 14, 37, 25, 0, time.UTC),
+			expected:  "eGbq9/2hcZsRlr1JV1Pi:20010308143725:46VIwd66mOFGG8IkbgnLlXnfnkU=",
+		},
+		{
+			// SHA-1 f2bb7160d2d3110eae98cdd180310daeee322adc
+			userKey:   "userid",
+			secretKey: "hunter2",
+			ua:        userAgent,
+			t:         time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			expected:  "userid:20200102030405:8rtxYNLTEQ6umM3RgDENru4yKtw=",
+		},
+	}
+
+	for _, tt := range tests {
+		sig := BuildSignature(tt.userKey, tt.secretKey, tt.ua, tt.t)
+		if sig != tt.expected {
+			t.Errorf("BuildSignature(%q, %q, %q, %v) = %q, expected %q", tt.userKey, tt.secretKey, tt.ua, tt.t, sig, tt.expected)
+		}
+	}
+}
+
+func TestNewRequest_SignatureMatchesBuildSignature(t *testing.T) {
+	c, err := New(nil, SetUserKey("userid"), SetSecretKey("hunter2"), SetClock(newFakeClock()))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	req, err := c.NewRequest(ctx, http.MethodGet, "v1/domains", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+
+	expected := BuildSignature("userid", "hunter2", userAgent, newFakeClock().Now())
+	if sig := req.Header.Get("X-Api-Signature"); sig != expected {
+		t.Errorf("X-Api-Signature = %q, expected %q", sig, expected)
+	}
+}
+
+func TestNewRequest_Accept(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/export", func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("Accept"); v != "text/vcard" {
+			t.Errorf("Request Accept = %v, expected %v", v, "text/vcard")
+		}
+		fmt.Fprint(w, "BEGIN:VCARD\r\nEND:VCARD\r\n")
+	})
+
+	acceptCtx := WithAccept(ctx, "text/vcard")
+	req, err := client.NewRequest(acceptCtx, http.MethodGet, "v1/domains/foo.com/export", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := client.Do(acceptCtx, req, &buf); err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+
+	if buf.String() != "BEGIN:VCARD\r\nEND:VCARD\r\n" {
+		t.Errorf("Do wrote %q, expected the raw vCard", buf.String())
+	}
+}
+
+func TestNewRequest_DefaultAccept(t *testing.T) {
+	req, err := client.NewRequest(ctx, http.MethodGet, "v1/domains", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+
+	if v := req.Header.Get("Accept"); v != mediaType {
+		t.Errorf("Request Accept = %v, expected %v", v, mediaType)
+	}
+}
+
+func TestCheckResponse_ServiceUnavailable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html><body><h1>Down for maintenance</h1></body></html>")
+	})
+
+	_, _, err := client.Domains.Show(ctx, "foo.com")
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("Domains.Show returned %v, expected %v", err, ErrServiceUnavailable)
+	}
+
+	var unavailable *ServiceUnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("Domains.Show returned %T, expected *ServiceUnavailableError", err)
+	}
+	if unavailable.RetryAfter != 2*time.Minute {
+		t.Errorf("RetryAfter = %v, expected %v", unavailable.RetryAfter, 2*time.Minute)
+	}
+	if strings.Contains(err.Error(), "<html>") {
+		t.Errorf("Error %q should not contain the HTML body", err.Error())
+	}
+}
+
+func TestCheckResponse_ServiceUnavailableJSON(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "overloaded"}`)),
+	}
+
+	err := CheckResponse(res)
+	if e, ok := err.(*ErrorResponse); !ok || e.Message != "overloaded" {
+		t.Errorf("CheckResponse returned %#v, expected an ErrorResponse", err)
+	}
+}
+
+func TestSignedRequest(t *testing.T) {
+	c, err := NewWithCredentials("userid", "hunter2")
+	if err != nil {
+		t.Fatalf("NewWithCredentials(): %v", err)
+	}
+
+	req, err := c.SignedRequest(ctx, http.MethodGet, "v1/domains", nil)
+	if err != nil {
+		t.Fatalf("SignedRequest returned error: %v", err)
+	}
+
+	if req.URL.String() != defaultBaseURL+"v1/domains" {
+		t.Errorf("SignedRequest URL = %v, expected %v", req.URL, defaultBaseURL+"v1/domains")
+	}
+
+	sig := req.Header.Get("X-Api-Signature")
+	if !strings.HasPrefix(sig, "userid:") || len(strings.Split(sig, ":")) != 3 {
+		t.Errorf("SignedRequest X-Api-Signature = %q, expected userid:timestamp:hash", sig)
+	}
+}
+
+func TestSetMetricsHook(t *testing.T) {
+	setup()
+	defer teardown()
+
+	type call struct {
+		method string
+		status int
+	}
+	var calls []call
+	hook := func(method string, status int, dur time.Duration) {
+		if dur < 0 {
+			t.Errorf("Metrics hook duration = %v, expected a non-negative duration", dur)
+		}
+		calls = append(calls, call{method, status})
+	}
+	if err := SetMetricsHook(hook)(client); err != nil {
+		t.Fatalf("SetMetricsHook(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	client.Domains.Show(ctx, "foo.com")
+	client.RackspaceEmailAliases.Delete(ctx, "foo.com", "bar")
+
+	expected := []call{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodDelete, http.StatusNotFound},
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Metrics hook calls = %+v, expected %+v", calls, expected)
+	}
+}
+
+func TestNewRequest_AcceptLanguage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetAcceptLanguage("de-DE")(client); err != nil {
+		t.Fatalf("SetAcceptLanguage(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("Accept-Language"); v != "de-DE" {
+			t.Errorf("Request Accept-Language = %v, expected %v", v, "de-DE")
+		}
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	if _, _, err := client.Domains.Show(ctx, "foo.com"); err != nil {
+		t.Errorf("Domains.Show returned error: %v", err)
+	}
+}
+
+func TestNewRequest_IdempotencyKey(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	var keys []string
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message": "try again"}`)
+		}
+	})
+
+	// The caller retries the failed Add with the same context.
+	keyCtx := WithIdempotencyKey(ctx, "add-bar-1")
+	if _, err := client.RackspaceEmailAliases.Add(keyCtx, "foo.com", "bar", []string{"a@foo.com"}); err == nil {
+		t.Fatalf("RackspaceEmailAliases.Add should have returned an error for a 500")
+	}
+	if _, err := client.RackspaceEmailAliases.Add(keyCtx, "foo.com", "bar", []string{"a@foo.com"}); err != nil {
+		t.Fatalf("RackspaceEmailAliases.Add returned error: %v", err)
+	}
+
+	expected := []string{"add-bar-1", "add-bar-1"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Idempotency-Key headers = %v, expected %v", keys, expected)
+	}
+}
+
+func TestWithCustomerID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/customers/123456/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name": "bar"}`)
+	})
+
+	_, _, err := client.RackspaceEmailAliases.Show(WithCustomerID(ctx, "123456"), "foo.com", "bar")
+	if err != nil {
+		t.Errorf("RackspaceEmailAliases.Show returned error: %v", err)
+	}
+}
+
+func TestWithCustomerID_Precedence(t *testing.T) {
+	c, err := New(nil, SetCustomerID("111111"))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	tests := []struct {
+		ctx      context.Context
+		path     string
+		expected string
+	}{
+		{ctx, "v1/domains", "v1/customers/111111/domains"},
+		{WithCustomerID(ctx, "222222"), "v1/domains?size=50", "v1/customers/222222/domains?size=50"},
+		{WithCustomerID(ctx, "222222"), "v1/domains/foo.com/ex/mailboxes/bar", "v1/customers/222222/domains/foo.com/ex/mailboxes/bar"},
+		{ctx, "v1/domainsfoo", "v1/domainsfoo"},
+		{ctx, "v1/other", "v1/other"},
+	}
+
+	for _, tt := range tests {
+		if got := c.customerPath(tt.ctx, tt.path); got != tt.expected {
+			t.Errorf("customerPath(%q) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+
+	c, err = New(nil)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if got := c.customerPath(ctx, "v1/domains"); got != "v1/domains" {
+		t.Errorf("customerPath without a customer ID = %q, expected %q", got, "v1/domains")
+	}
+}
+
+func TestNewRequest_IdempotencyKeyGET(t *testing.T) {
+	setup()
+	defer teardown()
+
+	req, err := client.NewRequest(WithIdempotencyKey(ctx, "key"), http.MethodGet, "v1/domains", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+
+	if v := req.Header.Get("Idempotency-Key"); v != "" {
+		t.Errorf("Request Idempotency-Key = %v, expected no header on a GET", v)
+	}
+}
+
+func TestWithRateLimitBypass(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	client.getLimiter.Allow()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if _, _, err := client.Domains.Show(tctx, "foo.com"); err == nil {
+		t.Fatalf("Domains.Show should have been throttled without the bypass")
+	}
+
+	if _, _, err := client.Domains.Show(WithRateLimitBypass(tctx), "foo.com"); err != nil {
+		t.Errorf("Domains.Show returned error with the bypass: %v", err)
+	}
+}
+
+func TestDo_LimiterWaitCancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	client.getLimiter.Allow()
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No request should have been sent")
+	})
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, _, err := client.Domains.Show(cctx, "foo.com")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Domains.Show returned %v, expected %v", err, context.Canceled)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "rate limiter wait: ") {
+		t.Errorf("Domains.Show returned %v, expected a rate limiter wait error", err)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.getLimiter = rate.NewLimiter(rate.Every(time.Hour), 2)
+	client.putPostDeleteLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com"}}`)
+	})
+
+	// Checking must not use up the burst
+	for i := 0; i < 5; i++ {
+		if !client.AllowGET() {
+			t.Fatalf("AllowGET returned false before any requests")
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Domains.Show(ctx, "foo.com"); err != nil {
+			t.Fatalf("Domains.Show returned error: %v", err)
+		}
+	}
+
+	if client.AllowGET() {
+		t.Errorf("AllowGET returned true after the burst was used up")
+	}
+	if !client.AllowMutate() {
+		t.Errorf("AllowMutate returned false, expected the mutate limiter to be unaffected")
+	}
+}
+
+func TestTimestamp_UnmarshalJSON(t *testing.T) {
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, v := range []string{
+		`"2020-01-02T03:04:05Z"`,
+		`"2020-01-02T04:04:05+01:00"`,
+		`"2020-01-02T03:04:05"`,
+		`"2020-01-02 03:04:05"`,
+		`"/Date(1577934245000)/"`,
+	} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(v), &ts); err != nil {
+			t.Errorf("Unmarshal(%s) returned error: %v", v, err)
+			continue
+		}
+		if !ts.Equal(expected) {
+			t.Errorf("Unmarshal(%s) = %v, expected %v", v, ts.Time, expected)
+		}
+	}
+
+	for _, v := range []string{`"yesterday"`, `"/Date(abc)/"`, `12345`} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(v), &ts); err == nil {
+			t.Errorf("Unmarshal(%s) should have returned an error", v)
+		}
+	}
+}
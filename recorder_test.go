@@ -0,0 +1,96 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	setup()
+
+	dir, err := ioutil.TempDir("", "reago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mux.HandleFunc("/v1/domains/foo.com", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain": {"name":"foo.com", "serviceType":"rsemail"}}`)
+	})
+	mux.HandleFunc("/v1/domains/bar.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	if err := SetRecorder(dir, RecordModeRecord)(client); err != nil {
+		t.Fatalf("SetRecorder(): %v", err)
+	}
+
+	recorded, _, err := client.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error: %v", err)
+	}
+	if _, _, err := client.Domains.Show(ctx, "bar.com"); !isNotFound(err) {
+		t.Fatalf("Domains.Show returned %v, expected a not found error", err)
+	}
+
+	// Replay with the server gone
+	teardown()
+
+	c, err := New(nil, SetRecorder(dir, RecordModeReplay))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	c.BaseURL = client.BaseURL
+
+	replayed, _, err := c.Domains.Show(ctx, "foo.com")
+	if err != nil {
+		t.Fatalf("Domains.Show returned error on replay: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("Domains.Show replayed %+v, expected %+v", replayed, recorded)
+	}
+
+	if _, _, err := c.Domains.Show(ctx, "bar.com"); !isNotFound(err) {
+		t.Errorf("Domains.Show replayed %v, expected a not found error", err)
+	}
+
+	if _, _, err := c.Domains.Show(ctx, "baz.com"); err == nil {
+		t.Errorf("Domains.Show should have returned an error without a recording")
+	}
+}
+
+func TestRecorder_CustomClientNotModified(t *testing.T) {
+	httpClient := &http.Client{}
+	c, err := New(httpClient, SetRecorder("testdata", RecordModeReplay))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	if httpClient.Transport != nil || c.client == httpClient {
+		t.Errorf("SetRecorder should not modify a custom HTTP client")
+	}
+}
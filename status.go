@@ -0,0 +1,133 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// statusProbePath is requested by ServiceStatus. The API has no status
+// resource, so the smallest possible domain listing stands in for one.
+const statusProbePath = domainsBasePath + "?size=1"
+
+// ServiceState is the health of the Rackspace Email API as seen by
+// ServiceStatus.
+type ServiceState string
+
+const (
+	// ServiceOperational means the API answered the probe successfully.
+	ServiceOperational ServiceState = "operational"
+
+	// ServiceDegraded means the API answered the probe with a throttling or
+	// server error, so calls may fail or need to be retried.
+	ServiceDegraded ServiceState = "degraded"
+
+	// ServiceDown means the API could not be reached or is unavailable, e.g.
+	// during a maintenance window.
+	ServiceDown ServiceState = "down"
+)
+
+// Status is the result of a ServiceStatus probe.
+type Status struct {
+	State ServiceState
+
+	// StatusCode of the probe's response, or 0 if none was received
+	StatusCode int
+
+	// Latency of the probe
+	Latency time.Duration
+}
+
+// ServiceStatus probes the API and reports whether it is operational,
+// degraded or down. The probe is a single GET request that waits on the GET
+// rate limiter and bypasses the cache. An error is only returned when the
+// probe cannot tell, e.g. when the context is cancelled or the credentials
+// are rejected.
+func (c *Client) ServiceStatus(ctx context.Context) (*Status, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, statusProbePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := c.clock.Now()
+	resp, err := c.Do(WithCacheBypass(ctx), req, nil)
+	status := &Status{Latency: c.clock.Now().Sub(start)}
+	if resp != nil {
+		status.StatusCode = resp.StatusCode
+	}
+
+	var errorResponse *ErrorResponse
+	switch {
+	case err == nil:
+		status.State = ServiceOperational
+	case ctx.Err() != nil:
+		return nil, err
+	case errors.Is(err, ErrServiceUnavailable):
+		status.State = ServiceDown
+	case errors.As(err, &errorResponse):
+		code := errorResponse.Response.StatusCode
+		if code == http.StatusServiceUnavailable {
+			status.State = ServiceDown
+		} else if code == http.StatusTooManyRequests || code >= 500 {
+			status.State = ServiceDegraded
+		} else {
+			return nil, err
+		}
+	default:
+		// The request did not get a response at all.
+		status.State = ServiceDown
+	}
+
+	return status, nil
+}
+
+// preflight returns ErrServiceUnavailable if SetPreflightStatusCheck is
+// enabled and the API is down, so that batch operations fail before making
+// any changes.
+func (c *Client) preflight(ctx context.Context) error {
+	if !c.preflightStatusCheck {
+		return nil
+	}
+
+	status, err := c.ServiceStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status.State == ServiceDown {
+		return ErrServiceUnavailable
+	}
+	return nil
+}
+
+// SetPreflightStatusCheck is a client option for probing the API with
+// ServiceStatus before the alias batch operations (ImportCSV, AddressMap,
+// ShowMany, ExportNDJSON and DeleteAll) and DomainAliasCounts, which then fail
+// with ErrServiceUnavailable if the API is down. A degraded API does not stop them. It costs one extra GET
+// per operation and is disabled by default.
+func SetPreflightStatusCheck(check bool) func(*Client) error {
+	return func(c *Client) error {
+		c.preflightStatusCheck = check
+		return nil
+	}
+}
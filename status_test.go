@@ -0,0 +1,199 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestServiceStatus_Operational(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if v := r.URL.Query().Get("size"); v != "1" {
+			t.Errorf("Request size = %v, expected %v", v, "1")
+		}
+		fmt.Fprint(w, `{"offset": 0, "size": 1, "total": 3, "domains": [{"name":"foo.com"}]}`)
+	})
+
+	status, err := client.ServiceStatus(ctx)
+	if err != nil {
+		t.Fatalf("ServiceStatus returned error: %v", err)
+	}
+
+	if status.State != ServiceOperational {
+		t.Errorf("ServiceStatus State = %v, expected %v", status.State, ServiceOperational)
+	}
+	if status.StatusCode != http.StatusOK {
+		t.Errorf("ServiceStatus StatusCode = %v, expected %v", status.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServiceStatus_Degraded(t *testing.T) {
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusInternalServerError} {
+		setup()
+
+		mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			fmt.Fprint(w, `{"message": "try again later"}`)
+		})
+
+		status, err := client.ServiceStatus(ctx)
+		if err != nil {
+			t.Fatalf("ServiceStatus returned error: %v", err)
+		}
+		if status.State != ServiceDegraded {
+			t.Errorf("ServiceStatus State for %d = %v, expected %v", code, status.State, ServiceDegraded)
+		}
+		if status.StatusCode != code {
+			t.Errorf("ServiceStatus StatusCode = %v, expected %v", status.StatusCode, code)
+		}
+
+		teardown()
+	}
+}
+
+func TestServiceStatus_Down(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html>Scheduled maintenance</html>")
+	})
+
+	status, err := client.ServiceStatus(ctx)
+	if err != nil {
+		t.Fatalf("ServiceStatus returned error: %v", err)
+	}
+	if status.State != ServiceDown {
+		t.Errorf("ServiceStatus State = %v, expected %v", status.State, ServiceDown)
+	}
+}
+
+func TestServiceStatus_Unreachable(t *testing.T) {
+	setup()
+	teardown()
+
+	status, err := client.ServiceStatus(ctx)
+	if err != nil {
+		t.Fatalf("ServiceStatus returned error: %v", err)
+	}
+	if status.State != ServiceDown || status.StatusCode != 0 {
+		t.Errorf("ServiceStatus returned %+v, expected %v without a status code", status, ServiceDown)
+	}
+}
+
+func TestServiceStatus_Unauthorized(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Invalid signature"}`)
+	})
+
+	if _, err := client.ServiceStatus(ctx); err == nil {
+		t.Errorf("ServiceStatus should have returned an error for a 401")
+	}
+}
+
+func TestSetPreflightStatusCheck(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetPreflightStatusCheck(true)(client); err != nil {
+		t.Fatalf("SetPreflightStatusCheck(): %v", err)
+	}
+
+	requests := 0
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html>Scheduled maintenance</html>")
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No aliases should have been listed while the API is down")
+	})
+
+	_, err := client.RackspaceEmailAliases.DeleteAll(ctx, "foo.com", "foo.com")
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("RackspaceEmailAliases.DeleteAll returned %v, expected %v", err, ErrServiceUnavailable)
+	}
+	if requests != 1 {
+		t.Errorf("Server received %d status probes, expected 1", requests)
+	}
+}
+
+func TestSetPreflightStatusCheck_ShowMany(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetPreflightStatusCheck(true)(client); err != nil {
+		t.Fatalf("SetPreflightStatusCheck(): %v", err)
+	}
+
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("No aliases should have been shown while the API is down")
+	})
+
+	results, errs := client.RackspaceEmailAliases.ShowMany(ctx, "foo.com", []string{"bar", "baz"}, 2)
+	if len(results) != 0 {
+		t.Errorf("RackspaceEmailAliases.ShowMany returned %v, expected no results", results)
+	}
+	for _, name := range []string{"bar", "baz"} {
+		if !errors.Is(errs[name], ErrServiceUnavailable) {
+			t.Errorf("RackspaceEmailAliases.ShowMany returned %v for %s, expected %v", errs[name], name, ErrServiceUnavailable)
+		}
+	}
+}
+
+func TestSetPreflightStatusCheck_DomainAliasCounts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetPreflightStatusCheck(true)(client); err != nil {
+		t.Fatalf("SetPreflightStatusCheck(): %v", err)
+	}
+
+	requests := 0
+	mux.HandleFunc("/v1/domains", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.DomainAliasCounts(ctx, 2)
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("DomainAliasCounts returned %v, expected %v", err, ErrServiceUnavailable)
+	}
+	if requests != 1 {
+		t.Errorf("Server received %d requests, expected 1 status probe", requests)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RequestTemplate builds requests for an endpoint that is called repeatedly
+// with different path arguments, e.g. one the services do not wrap yet:
+//
+//	t := reago.NewRequestTemplate(http.MethodGet, "v1/domains/%s/rs/aliases/%s", nil)
+//	req, err := t.NewRequest(ctx, client, nil, "example.com", "sales")
+//
+// The requests are built with Client.NewRequest, so they are signed and ready
+// to pass to Client.Do.
+type RequestTemplate struct {
+	// Method is the HTTP method of the requests.
+	Method string
+
+	// Path is a fmt pattern for the path of the requests, relative to the
+	// client's BaseURL, with a %s verb for each path argument.
+	Path string
+
+	// Options are the default query options, encoded like PageOptions. They
+	// are not added when nil.
+	Options interface{}
+}
+
+// NewRequestTemplate returns a RequestTemplate for method and the path
+// pattern path with the default query options opt, which may be nil.
+func NewRequestTemplate(method, path string, opt interface{}) *RequestTemplate {
+	return &RequestTemplate{Method: method, Path: path, Options: opt}
+}
+
+// NewRequest builds a request from the template for c, filling the path
+// pattern with args, each of them path escaped, and sending body as the form
+// body of POST and PUT requests. It returns an ArgError if the number of
+// args does not match the path pattern.
+func (t *RequestTemplate) NewRequest(ctx context.Context, c *Client, body map[string]string, args ...interface{}) (*http.Request, error) {
+	escaped := make([]interface{}, len(args))
+	for i, arg := range args {
+		escaped[i] = url.PathEscape(fmt.Sprint(arg))
+	}
+
+	path := fmt.Sprintf(t.Path, escaped...)
+	if strings.Contains(path, "%!") {
+		return nil, NewArgError("args", fmt.Sprintf("%d arguments do not match the path %q", len(args), t.Path))
+	}
+
+	if t.Options != nil {
+		var err error
+		path, err = addOptions(path, t.Options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.NewRequest(ctx, t.Method, path, body)
+}
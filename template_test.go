@@ -0,0 +1,90 @@
+// Copyright © 2019 Patrick Lawrence <patrick.lawrence@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reago
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRequestTemplate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/domains/foo.com/rs/aliases/bar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if v := r.URL.Query().Get("size"); v != "10" {
+			t.Errorf("Request size = %v, expected %v", v, "10")
+		}
+		if r.Header.Get("X-Api-Signature") == "" {
+			t.Errorf("Request X-Api-Signature should be set")
+		}
+		fmt.Fprint(w, `{"name": "bar", "emailAddressList": {"emailAddress": ["baz@bar.com"]}}`)
+	})
+
+	tmpl := NewRequestTemplate(http.MethodGet, "v1/domains/%s/rs/aliases/%s", &PageOptions{Size: 10})
+	req, err := tmpl.NewRequest(ctx, client, nil, "foo.com", "bar")
+	if err != nil {
+		t.Fatalf("RequestTemplate.NewRequest returned error: %v", err)
+	}
+
+	alias := new(RackspaceEmailAliasShow)
+	if _, err := client.Do(ctx, req, alias); err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+
+	expected := &RackspaceEmailAliasShow{Name: "bar", EmailAddressList: EmailAddress{Addresses: []string{"baz@bar.com"}}}
+	if !reflect.DeepEqual(alias, expected) {
+		t.Errorf("Do returned %+v, expected %+v", alias, expected)
+	}
+}
+
+func TestRequestTemplate_EscapesArgs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	tmpl := NewRequestTemplate(http.MethodDelete, "v1/domains/%s/rs/aliases/%s", nil)
+	req, err := tmpl.NewRequest(ctx, client, nil, "foo.com", "a/b?c")
+	if err != nil {
+		t.Fatalf("RequestTemplate.NewRequest returned error: %v", err)
+	}
+
+	if expected := "/v1/domains/foo.com/rs/aliases/a%2Fb%3Fc"; req.URL.EscapedPath() != expected {
+		t.Errorf("Request path = %v, expected %v", req.URL.EscapedPath(), expected)
+	}
+	if req.URL.RawQuery != "" {
+		t.Errorf("Request query = %v, expected none", req.URL.RawQuery)
+	}
+}
+
+func TestRequestTemplate_WrongArgs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	tmpl := NewRequestTemplate(http.MethodGet, "v1/domains/%s/rs/aliases/%s", nil)
+	for _, args := range [][]interface{}{{"foo.com"}, {"foo.com", "bar", "baz"}} {
+		if _, err := tmpl.NewRequest(ctx, client, nil, args...); err == nil {
+			t.Errorf("RequestTemplate.NewRequest should have returned an error for args %v", args)
+		}
+	}
+}